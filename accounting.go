@@ -0,0 +1,128 @@
+package limitgroup
+
+import (
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// TaskCost aggregates the resources used by subtasks with the same name, as
+// recorded with WithResourceAccounting.
+type TaskCost struct {
+	// Tasks is the number of subtasks measured.
+	Tasks int
+	// AllocBytes is the number of bytes allocated on the heap while the
+	// subtasks ran.
+	AllocBytes uint64
+	// CPUTime is the user CPU time spent while the subtasks ran.
+	CPUTime time.Duration
+}
+
+// Names of the runtime/metrics sampled around each subtask.
+const (
+	allocsMetric = "/gc/heap/allocs:bytes"
+	cpuMetric    = "/cpu/classes/user:cpu-seconds"
+)
+
+// accounting records the TaskCost of each subtask name. The process-wide
+// metrics are sampled whenever a measured subtask starts or finishes, and
+// the usage between two samples is divided evenly among the subtasks that
+// were running throughout it, so that overlapping subtasks do not each
+// claim all of it.
+type accounting struct {
+	mu      sync.Mutex
+	costs   map[string]TaskCost
+	last    costSample
+	running map[string]int // measured subtasks running, by name
+	n       int            // total of running
+}
+
+// costSample is a reading of the metrics used for accounting.
+type costSample struct {
+	alloc uint64
+	cpu   float64
+}
+
+// sampleCost reads the current values of the metrics used for accounting.
+func sampleCost() costSample {
+	samples := []metrics.Sample{{Name: allocsMetric}, {Name: cpuMetric}}
+	metrics.Read(samples)
+
+	var s costSample
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		s.alloc = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindFloat64 {
+		s.cpu = samples[1].Value.Float64()
+	}
+	return s
+}
+
+// account starts measuring the resources used by t and returns a function
+// that records them once t has finished. It does nothing unless the Group
+// was created with WithResourceAccounting.
+func (lg *Group) account(t task) func() {
+	a := lg.accounting
+	if a == nil {
+		return func() {}
+	}
+
+	a.mu.Lock()
+	a.advance(sampleCost())
+	a.running[t.name]++
+	a.n++
+	a.mu.Unlock()
+
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		a.advance(sampleCost())
+		if a.running[t.name]--; a.running[t.name] == 0 {
+			delete(a.running, t.name)
+		}
+		a.n--
+
+		c := a.costs[t.name]
+		c.Tasks++
+		a.costs[t.name] = c
+	}
+}
+
+// advance divides the usage since the last sample among the subtasks
+// running, then makes now the last sample. Usage while no measured subtask
+// runs is not attributed to any. It must be called with a.mu held.
+func (a *accounting) advance(now costSample) {
+	defer func() { a.last = now }()
+	if a.n == 0 {
+		return
+	}
+
+	var alloc uint64
+	if now.alloc > a.last.alloc {
+		alloc = now.alloc - a.last.alloc
+	}
+	var cpu float64
+	if now.cpu > a.last.cpu {
+		cpu = now.cpu - a.last.cpu
+	}
+	for name, k := range a.running {
+		share := float64(k) / float64(a.n)
+		c := a.costs[name]
+		c.AllocBytes += uint64(float64(alloc) * share)
+		c.CPUTime += time.Duration(cpu * share * float64(time.Second))
+		a.costs[name] = c
+	}
+}
+
+// snapshot returns a copy of the recorded costs.
+func (a *accounting) snapshot() map[string]TaskCost {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	costs := make(map[string]TaskCost, len(a.costs))
+	for name, c := range a.costs {
+		costs[name] = c
+	}
+	return costs
+}
@@ -0,0 +1,85 @@
+package limitgroup
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// durationSamples is the number of recent subtask durations kept for
+	// deadline-aware admission.
+	durationSamples = 256
+	// minDurationSamples is the number of durations that must be observed
+	// before deadline-aware admission starts rejecting subtasks.
+	minDurationSamples = 10
+)
+
+// durations is a fixed-size ring of recently observed subtask durations.
+type durations struct {
+	mu      sync.Mutex
+	samples [durationSamples]time.Duration
+	n       int
+}
+
+// observe records the duration of a finished subtask.
+func (d *durations) observe(v time.Duration) {
+	d.mu.Lock()
+	d.samples[d.n%durationSamples] = v
+	d.n++
+	d.mu.Unlock()
+}
+
+// percentile returns the p'th percentile, in the range [0, 1], of the
+// recorded durations, and false if too few have been recorded.
+func (d *durations) percentile(p float64) (time.Duration, bool) {
+	d.mu.Lock()
+	n := d.n
+	if n > durationSamples {
+		n = durationSamples
+	}
+	sorted := append([]time.Duration(nil), d.samples[:n]...)
+	d.mu.Unlock()
+
+	if n < minDurationSamples {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(p * float64(n-1))
+	return sorted[i], true
+}
+
+// admit returns ErrGroupClosed if the Group's Wait method has returned,
+// ErrMaxTasks if the Group has accepted as many subtasks as it allows, or
+// ErrDeadlineWouldExceed if deadline-aware admission is enabled and ctx does
+// not leave enough time to run a typical subtask. An admitted subtask takes
+// a place in the WithMaxTasks budget, which must be given back with unadmit
+// if it then fails to start.
+func (lg *Group) admit(ctx context.Context) error {
+	if atomic.LoadInt32(&lg.closed) != 0 {
+		return ErrGroupClosed
+	}
+	if lg.durations != nil {
+		if deadline, ok := ctx.Deadline(); ok {
+			expected, ok := lg.durations.percentile(lg.admitPercentile)
+			if ok && time.Until(deadline) < expected {
+				return ErrDeadlineWouldExceed
+			}
+		}
+	}
+	if lg.maxTasks > 0 && atomic.AddInt64(&lg.admitted, 1) > lg.maxTasks {
+		lg.unadmit()
+		return ErrMaxTasks
+	}
+	return nil
+}
+
+// unadmit gives back the place in the WithMaxTasks budget taken by admit for
+// a subtask that did not start after all.
+func (lg *Group) unadmit() {
+	if lg.maxTasks > 0 {
+		atomic.AddInt64(&lg.admitted, -1)
+	}
+}
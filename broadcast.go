@@ -0,0 +1,69 @@
+package limitgroup
+
+import (
+	"context"
+	"time"
+)
+
+// Broadcast receives values from in until it is closed and delivers each of
+// them to every consumer. Each consumer sees the values in the order they
+// were received and handles one at a time, while calls for different
+// consumers run concurrently, with at most limit in flight at once. As with
+// WithContext, a limit less than or equal to zero selects the default limit.
+//
+// Values are delivered to every consumer before the next one is received, so
+// the slowest consumer sets the pace. The first consumer call to return a
+// non-nil error cancels the context passed to the others and stops the
+// broadcast; Broadcast returns once every call has returned, with that error
+// or, if ctx was done first, with ctx's error.
+func Broadcast[T any](ctx context.Context, limit int64, in <-chan T, consumers ...func(context.Context, T) error) error {
+	parent := ctx
+	lg, ctx := WithContext(ctx, limit)
+
+	chans := make([]chan T, len(consumers))
+	for i, fn := range consumers {
+		ch, fn := make(chan T), fn
+		chans[i] = ch
+		lg.spawn(func() error {
+			for v := range ch {
+				v := v
+				t := lg.newTask("")
+				if err := lg.acquire(ctx, &t); err != nil {
+					lg.finishTask(t, time.Time{}, err)
+					return err
+				}
+				if err := lg.call(t, func() error { return fn(ctx, v) }); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+recv:
+	for {
+		select {
+		case <-ctx.Done():
+			break recv
+		case v, ok := <-in:
+			if !ok {
+				break recv
+			}
+			for _, ch := range chans {
+				select {
+				case ch <- v:
+				case <-ctx.Done():
+					break recv
+				}
+			}
+		}
+	}
+	for _, ch := range chans {
+		close(ch)
+	}
+
+	if err := lg.Wait(); err != nil {
+		return err
+	}
+	return parent.Err()
+}
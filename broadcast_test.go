@@ -0,0 +1,85 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcast(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got = make([][]int, 3)
+	)
+	consumers := make([]func(context.Context, int) error, len(got))
+	for i := range consumers {
+		consumers[i] = func(_ context.Context, v int) error {
+			mu.Lock()
+			got[i] = append(got[i], v)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	if err := Broadcast(context.Background(), 2, feed(1, 2, 3, 4, 5), consumers...); err != nil {
+		t.Fatalf("Broadcast() = %v, want nil", err)
+	}
+	for i, values := range got {
+		if len(values) != 5 {
+			t.Fatalf("consumer %d saw %v, want [1 2 3 4 5]", i, values)
+		}
+		for j, v := range values {
+			if v != j+1 {
+				t.Fatalf("consumer %d saw %v, want [1 2 3 4 5]", i, values)
+			}
+		}
+	}
+}
+
+func TestBroadcastError(t *testing.T) {
+	errBad := errors.New("bad")
+	in, stop := make(chan int), make(chan struct{})
+	defer close(stop)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case in <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	err := Broadcast(context.Background(), 2, in,
+		func(_ context.Context, v int) error {
+			if v == 2 {
+				return errBad
+			}
+			return nil
+		},
+		func(ctx context.Context, v int) error {
+			if v == 2 {
+				// Only returns once the failure cancels the call.
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		})
+	if !errors.Is(err, errBad) {
+		t.Errorf("Broadcast() = %v, want %v", err, errBad)
+	}
+}
+
+func TestBroadcastCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	// in is never closed, so only ctx can end the broadcast.
+	err := Broadcast(ctx, 1, in, func(context.Context, int) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Broadcast() = %v, want context.Canceled", err)
+	}
+}
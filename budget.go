@@ -0,0 +1,37 @@
+package limitgroup
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// startBudget starts the Group's time budget, if it has one and it has not
+// been started yet.
+func (lg *Group) startBudget() {
+	lg.budgetOnce.Do(func() {
+		if lg.budget > 0 && !lg.wrapped {
+			lg.budgetTimer = time.AfterFunc(lg.budget, func() {
+				atomic.StoreInt32(&lg.budgetSpent, 1)
+				lg.cancel()
+			})
+		}
+	})
+}
+
+// stopBudget stops the Group's time budget from running out, or from ever
+// being started.
+func (lg *Group) stopBudget() {
+	lg.budgetOnce.Do(func() {})
+	if lg.budgetTimer != nil {
+		lg.budgetTimer.Stop()
+	}
+}
+
+// budgetErr returns ErrTimeBudgetExceeded in place of err if err is a context
+// error caused by the time budget running out.
+func (lg *Group) budgetErr(err error) error {
+	if atomic.LoadInt32(&lg.budgetSpent) != 0 && isContextErr(err) {
+		return ErrTimeBudgetExceeded
+	}
+	return err
+}
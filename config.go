@@ -0,0 +1,179 @@
+package limitgroup
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Config describes the settings of a Group declaratively, e.g. as loaded
+// from a service's configuration file. The zero Config describes a Group with
+// the default limit and no optional behavior.
+type Config struct {
+	// Limit is the maximum number of in-flight subtasks. Zero selects the
+	// default, as for WithContext.
+	Limit int64
+	// TrackGOMAXPROCS derives the default limit from GOMAXPROCS; see
+	// WithGOMAXPROCS. It requires Limit to be zero.
+	TrackGOMAXPROCS bool
+	// Burst and BurstWindow configure burst capacity; see WithBurst.
+	Burst       int64
+	BurstWindow time.Duration
+	// RampStart, RampStep, and RampInterval, if RampInterval is non-zero,
+	// configure ramp-up; see WithRampUp.
+	RampStart    int64
+	RampStep     int64
+	RampInterval time.Duration
+	// StrictFIFO enforces strict FIFO slot grants; see WithStrictFIFO.
+	StrictFIFO bool
+	// TaskResults records subtask outcomes for WaitAll; see WithTaskResults.
+	TaskResults bool
+	// MaxTaskErrors, if non-zero, caps the errors kept for TaskResults; see
+	// WithMaxTaskErrors.
+	MaxTaskErrors int
+	// DeadlinePercentile, if non-zero, enables deadline-aware admission at
+	// the given percentile; see WithDeadlineAdmission.
+	DeadlinePercentile float64
+	// TenantWeights and TenantQuotas configure scheduling between tenants;
+	// see WithTenantWeights and WithTenantQuotas.
+	TenantWeights map[string]float64
+	TenantQuotas  map[string]Quota
+	// EventBuffer, if non-zero, enables the event stream with the given
+	// buffer size; see WithEvents.
+	EventBuffer int
+	// JournalSize, if non-zero, enables the journal with the given size; see
+	// WithJournal.
+	JournalSize int
+	// MaxTasks, if non-zero, caps the number of subtasks the Group accepts;
+	// see WithMaxTasks.
+	MaxTasks int64
+	// TimeBudget, if non-zero, bounds the Group's running time from its
+	// first subtask; see WithTimeBudget.
+	TimeBudget time.Duration
+	// Labels tag the Group's telemetry; see WithLabels.
+	Labels map[string]string
+	// Resources defines named resources and their capacities; see
+	// WithResources.
+	Resources map[string]int64
+	// ResourceAccounting records the resources used by subtasks; see
+	// WithResourceAccounting.
+	ResourceAccounting bool
+}
+
+// Validate reports the first problem found with the settings in c, if any.
+func (c Config) Validate() error {
+	switch {
+	case c.Limit < 0:
+		return c.invalid("negative Limit %d", c.Limit)
+	case c.TrackGOMAXPROCS && c.Limit != 0:
+		return c.invalid("TrackGOMAXPROCS requires the default Limit")
+	case c.Burst < 0:
+		return c.invalid("negative Burst %d", c.Burst)
+	case c.BurstWindow < 0:
+		return c.invalid("negative BurstWindow %v", c.BurstWindow)
+	case c.BurstWindow != 0 && c.Burst == 0:
+		return c.invalid("BurstWindow set without Burst")
+	case c.RampStart < 0 || c.RampStep < 0 || c.RampInterval < 0:
+		return c.invalid("negative ramp-up setting")
+	case (c.RampStart != 0 || c.RampStep != 0) && c.RampInterval == 0:
+		return c.invalid("ramp-up set without RampInterval")
+	case c.DeadlinePercentile < 0 || c.DeadlinePercentile > 1:
+		return c.invalid("DeadlinePercentile %v outside [0, 1]", c.DeadlinePercentile)
+	case c.EventBuffer < 0:
+		return c.invalid("negative EventBuffer %d", c.EventBuffer)
+	case c.MaxTaskErrors < 0:
+		return c.invalid("negative MaxTaskErrors %d", c.MaxTaskErrors)
+	case c.MaxTaskErrors != 0 && !c.TaskResults:
+		return c.invalid("MaxTaskErrors set without TaskResults")
+	case c.JournalSize < 0:
+		return c.invalid("negative JournalSize %d", c.JournalSize)
+	case c.MaxTasks < 0:
+		return c.invalid("negative MaxTasks %d", c.MaxTasks)
+	case c.TimeBudget < 0:
+		return c.invalid("negative TimeBudget %v", c.TimeBudget)
+	}
+	for tenant, w := range c.TenantWeights {
+		if w <= 0 {
+			return c.invalid("non-positive weight %v for tenant %q", w, tenant)
+		}
+	}
+	for tenant, q := range c.TenantQuotas {
+		if q.MaxInFlight < 0 || q.MaxQueued < 0 {
+			return c.invalid("negative quota for tenant %q", tenant)
+		}
+	}
+	for name, capacity := range c.Resources {
+		if capacity <= 0 {
+			return c.invalid("non-positive capacity %d for resource %q", capacity, name)
+		}
+	}
+	return nil
+}
+
+func (Config) invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("limitgroup: invalid config: "+format, args...)
+}
+
+// Options returns the Options equivalent to c, for use with WithContext.
+func (c Config) Options() []Option {
+	var opts []Option
+	if c.TrackGOMAXPROCS {
+		opts = append(opts, WithGOMAXPROCS())
+	}
+	if c.Burst > 0 {
+		opts = append(opts, WithBurst(c.Burst, c.BurstWindow))
+	}
+	if c.RampInterval > 0 {
+		opts = append(opts, WithRampUp(c.RampStart, c.RampStep, c.RampInterval))
+	}
+	if c.StrictFIFO {
+		opts = append(opts, WithStrictFIFO())
+	}
+	if c.TaskResults {
+		opts = append(opts, WithTaskResults())
+	}
+	if c.MaxTaskErrors > 0 {
+		opts = append(opts, WithMaxTaskErrors(c.MaxTaskErrors))
+	}
+	if c.DeadlinePercentile != 0 {
+		opts = append(opts, WithDeadlineAdmission(c.DeadlinePercentile))
+	}
+	if c.TenantWeights != nil {
+		opts = append(opts, WithTenantWeights(c.TenantWeights))
+	}
+	if c.TenantQuotas != nil {
+		opts = append(opts, WithTenantQuotas(c.TenantQuotas))
+	}
+	if c.EventBuffer > 0 {
+		opts = append(opts, WithEvents(c.EventBuffer))
+	}
+	if c.JournalSize > 0 {
+		opts = append(opts, WithJournal(c.JournalSize))
+	}
+	if c.MaxTasks > 0 {
+		opts = append(opts, WithMaxTasks(c.MaxTasks))
+	}
+	if c.TimeBudget > 0 {
+		opts = append(opts, WithTimeBudget(c.TimeBudget))
+	}
+	if c.Labels != nil {
+		opts = append(opts, WithLabels(c.Labels))
+	}
+	if c.Resources != nil {
+		opts = append(opts, WithResources(c.Resources))
+	}
+	if c.ResourceAccounting {
+		opts = append(opts, WithResourceAccounting())
+	}
+	return opts
+}
+
+// New validates c and returns a new Group configured by it, and the
+// associated Context derived from ctx, exactly like WithContext.
+func (c Config) New(ctx context.Context) (*Group, context.Context, error) {
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+	lg, ctx := WithContext(ctx, c.Limit, c.Options()...)
+	return lg, ctx, nil
+}
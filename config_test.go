@@ -0,0 +1,98 @@
+package limitgroup
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "zero", config: Config{}},
+		{
+			name: "everything set",
+			config: Config{
+				Limit:              4,
+				Burst:              2,
+				BurstWindow:        time.Second,
+				RampStart:          1,
+				RampStep:           1,
+				RampInterval:       time.Second,
+				TaskResults:        true,
+				MaxTaskErrors:      10,
+				DeadlinePercentile: 0.9,
+				TenantWeights:      map[string]float64{"a": 2},
+				TenantQuotas:       map[string]Quota{"a": {MaxInFlight: 1}},
+				EventBuffer:        8,
+				JournalSize:        8,
+				MaxTasks:           100,
+				TimeBudget:         time.Minute,
+				Labels:             map[string]string{"job": "test"},
+				Resources:          map[string]int64{"conn": 2},
+				ResourceAccounting: true,
+			},
+		},
+		{name: "negative Limit", config: Config{Limit: -1}, wantErr: "negative Limit"},
+		{name: "GOMAXPROCS with Limit", config: Config{Limit: 1, TrackGOMAXPROCS: true}, wantErr: "TrackGOMAXPROCS"},
+		{name: "BurstWindow without Burst", config: Config{BurstWindow: time.Second}, wantErr: "BurstWindow set without Burst"},
+		{name: "ramp-up without interval", config: Config{RampStart: 1}, wantErr: "without RampInterval"},
+		{name: "percentile above one", config: Config{DeadlinePercentile: 1.5}, wantErr: "DeadlinePercentile"},
+		{name: "MaxTaskErrors without TaskResults", config: Config{MaxTaskErrors: 1}, wantErr: "without TaskResults"},
+		{name: "non-positive weight", config: Config{TenantWeights: map[string]float64{"a": 0}}, wantErr: `tenant "a"`},
+		{name: "negative MaxTasks", config: Config{MaxTasks: -1}, wantErr: "negative MaxTasks"},
+		{name: "negative TimeBudget", config: Config{TimeBudget: -time.Second}, wantErr: "negative TimeBudget"},
+		{name: "zero capacity", config: Config{Resources: map[string]int64{"conn": 0}}, wantErr: `resource "conn"`},
+		{name: "negative capacity", config: Config{Resources: map[string]int64{"mem": -1}}, wantErr: `resource "mem"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigNew(t *testing.T) {
+	c := Config{
+		Limit:              3,
+		Resources:          map[string]int64{"conn": 1},
+		ResourceAccounting: true,
+		Labels:             map[string]string{"job": "test"},
+	}
+	lg, _, err := c.New(context.Background())
+	if err != nil {
+		t.Fatalf("New() = %v, want nil", err)
+	}
+	lg.GoResources(map[string]int64{"conn": 1}, func() error { return nil })
+	if err := lg.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+
+	st := lg.Stats()
+	if st.Limit != 3 {
+		t.Errorf("Limit = %d, want 3", st.Limit)
+	}
+	if st.Costs[""].Tasks != 1 {
+		t.Errorf("Costs = %v, want one unnamed subtask measured", st.Costs)
+	}
+	if st.Labels["job"] != "test" {
+		t.Errorf("Labels = %v, want job=test", st.Labels)
+	}
+
+	if _, _, err := (Config{Resources: map[string]int64{"conn": 0}}).New(context.Background()); err == nil {
+		t.Error("New() with an invalid Config = nil, want an error")
+	}
+}
@@ -0,0 +1,91 @@
+package limitgroup
+
+import (
+	"sync"
+	"time"
+)
+
+// A Debouncer coalesces rapid, repeated submissions for the same key into a
+// single subtask on a Group. A subtask is only started once window has
+// elapsed without another submission for its key, and then only the most
+// recently submitted function for that key is run.
+type Debouncer[K comparable] struct {
+	lg     *Group
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[K]*debounced
+}
+
+type debounced struct {
+	f        func() error
+	deadline time.Time
+}
+
+// NewDebouncer returns a Debouncer that runs subtasks on the given Group.
+func NewDebouncer[K comparable](lg *Group, window time.Duration) *Debouncer[K] {
+	return &Debouncer[K]{
+		lg:      lg,
+		window:  window,
+		pending: make(map[K]*debounced),
+	}
+}
+
+// Go submits f for key, replacing any function already pending for key and
+// restarting its window. It never blocks. Once the window elapses, f is run
+// under the Group's limit exactly as if passed to Go; if the Group's context
+// is done first, it is dropped without affecting the Group.
+func (d *Debouncer[K]) Go(key K, f func() error) {
+	deadline := time.Now().Add(d.window)
+
+	d.mu.Lock()
+	if p, ok := d.pending[key]; ok {
+		p.f, p.deadline = f, deadline
+		d.mu.Unlock()
+		return
+	}
+	p := &debounced{f: f, deadline: deadline}
+	d.pending[key] = p
+	d.mu.Unlock()
+
+	lg := d.lg
+	lg.spawn(func() error {
+		f, ok := d.await(key, p)
+		t := lg.newTask("")
+		if !ok {
+			lg.shedTask(t, lg.ctx.Err())
+			return nil
+		}
+		if err := lg.acquire(lg.ctx, &t); err != nil {
+			return lg.reject(t, err)
+		}
+		return lg.call(t, f)
+	})
+}
+
+// await blocks until the window for key has elapsed, then returns the latest
+// function submitted for it. It returns false if the Group's context is done
+// first.
+func (d *Debouncer[K]) await(key K, p *debounced) (func() error, bool) {
+	for {
+		d.mu.Lock()
+		remaining := time.Until(p.deadline)
+		if remaining <= 0 {
+			delete(d.pending, key)
+			d.mu.Unlock()
+			return p.f, true
+		}
+		d.mu.Unlock()
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-d.lg.ctx.Done():
+			timer.Stop()
+			d.mu.Lock()
+			delete(d.pending, key)
+			d.mu.Unlock()
+			return nil, false
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package limitgroup
+
+import "errors"
+
+var (
+	// ErrShed is returned, or recorded in a TaskResult, when a subtask is
+	// turned away without affecting the Group, e.g. by TryGo when no slot is
+	// free. More specific reasons for shedding a subtask, such as
+	// ErrQuotaExceeded, match ErrShed with errors.Is.
+	ErrShed = errors.New("limitgroup: subtask shed")
+
+	// ErrQuotaExceeded is returned when a subtask is rejected because its
+	// tenant already has as many callers waiting for a slot as its Quota
+	// allows.
+	ErrQuotaExceeded error = &shedError{"limitgroup: tenant quota exceeded"}
+
+	// ErrDeadlineWouldExceed is returned when a subtask is rejected because it
+	// is not expected to finish before its context's deadline.
+	ErrDeadlineWouldExceed error = &shedError{"limitgroup: subtask would exceed the context deadline"}
+
+	// ErrGroupClosed is returned when a subtask is submitted to a Group, or a
+	// Reservation made from it, after its Wait method has returned.
+	ErrGroupClosed = errors.New("limitgroup: group closed")
+
+	// ErrMaxTasks is returned when a subtask is submitted to a Group that
+	// has already accepted as many as WithMaxTasks allows.
+	ErrMaxTasks = errors.New("limitgroup: maximum number of subtasks exceeded")
+
+	// ErrTimeBudgetExceeded is returned by Wait when a Group was canceled
+	// because its time budget, set with WithTimeBudget, ran out.
+	ErrTimeBudgetExceeded = errors.New("limitgroup: time budget exceeded")
+
+	// ErrSuppressed replaces the error of a failed subtask in the TaskResult
+	// reported by WaitAll once the cap set with WithMaxTaskErrors is reached.
+	ErrSuppressed = errors.New("limitgroup: subtask error suppressed")
+
+	// ErrNoFutures is the error of the Future returned by Any when it is
+	// given no futures.
+	ErrNoFutures = errors.New("limitgroup: no futures given")
+)
+
+// shedError is a specific reason for shedding a subtask.
+type shedError struct {
+	msg string
+}
+
+func (e *shedError) Error() string {
+	return e.msg
+}
+
+func (e *shedError) Is(target error) bool {
+	return target == ErrShed
+}
@@ -0,0 +1,70 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+)
+
+// An ErrorPolicy chooses which error Wait returns when more than one
+// subtask fails. Each failure is offered to the policy in the order the
+// subtasks finish: it reports whether candidate should replace current as the
+// error to return.
+//
+// Errors passed to DoneWith are offered with an ID of -1.
+type ErrorPolicy func(current, candidate TaskResult) bool
+
+var (
+	// FirstError returns the first error to occur, as errgroup does. This is
+	// the default.
+	FirstError ErrorPolicy = func(current, candidate TaskResult) bool {
+		return false
+	}
+
+	// FirstSubmitted returns the error of the earliest submitted subtask that
+	// failed, regardless of when it failed. Errors from DoneWith rank after
+	// those of subtasks.
+	FirstSubmitted ErrorPolicy = func(current, candidate TaskResult) bool {
+		if current.ID < 0 {
+			return candidate.ID >= 0
+		}
+		return candidate.ID >= 0 && candidate.ID < current.ID
+	}
+
+	// PreferNonContext returns the first error to occur unless it is a
+	// context error, i.e. context.Canceled or context.DeadlineExceeded, in
+	// which case it returns the first other error, if any. This avoids Wait
+	// reporting the cancellation caused by a failure rather than the failure
+	// itself.
+	PreferNonContext ErrorPolicy = func(current, candidate TaskResult) bool {
+		return isContextErr(current.Err) && !isContextErr(candidate.Err)
+	}
+)
+
+// BySeverity returns an ErrorPolicy that returns the error with the highest
+// severity, as reported by the given function, and the first to occur of
+// those with equal severity.
+func BySeverity(severity func(error) int) ErrorPolicy {
+	return func(current, candidate TaskResult) bool {
+		return severity(candidate.Err) > severity(current.Err)
+	}
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// selectError records the failure described by res and offers it to the
+// Group's ErrorPolicy, if any.
+func (lg *Group) selectError(res TaskResult) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if lg.firstErr == nil {
+		lg.firstErr = res.Err
+	}
+	if lg.errPolicy == nil {
+		return
+	}
+	if lg.selected.Err == nil || lg.errPolicy(lg.selected, res) {
+		lg.selected = res
+	}
+}
@@ -0,0 +1,115 @@
+package limitgroup
+
+import (
+	"sync"
+	"time"
+)
+
+// An EventKind identifies what happened in an Event.
+type EventKind int
+
+// Kinds of Event emitted by a Group.
+const (
+	// EventSubmitted is emitted when a subtask is submitted to the Group.
+	EventSubmitted EventKind = iota
+	// EventStarted is emitted when a subtask starts running.
+	EventStarted
+	// EventFinished is emitted when a subtask returns a nil error.
+	EventFinished
+	// EventFailed is emitted when a subtask returns a non-nil error, or could
+	// not be started because of an error that cancels the Group.
+	EventFailed
+	// EventShed is emitted when a subtask is turned away without affecting
+	// the Group, e.g. by GoWithin or a tenant quota.
+	EventShed
+	// EventLimitChanged is emitted when the Group's limit changes.
+	EventLimitChanged
+)
+
+var eventKindNames = [...]string{
+	EventSubmitted:    "submitted",
+	EventStarted:      "started",
+	EventFinished:     "finished",
+	EventFailed:       "failed",
+	EventShed:         "shed",
+	EventLimitChanged: "limit-changed",
+}
+
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventKindNames) {
+		return "unknown"
+	}
+	return eventKindNames[k]
+}
+
+// An Event describes something that happened in a Group.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// TaskID and TaskName identify the subtask the event relates to, as in
+	// TaskResult. TaskID is -1 for EventLimitChanged.
+	TaskID   int
+	TaskName string
+	// Err is the error that caused EventFailed or EventShed.
+	Err error
+	// Limit is the Group's new limit for EventLimitChanged.
+	Limit int64
+	// Labels are the Group's labels, set with WithLabels. The map is shared
+	// and must not be modified.
+	Labels map[string]string
+}
+
+// events delivers Events to a buffered channel without ever blocking the
+// Group: events are dropped if the channel is full.
+type events struct {
+	mu     sync.Mutex
+	ch     chan Event
+	closed bool
+}
+
+func newEvents(buffer int) *events {
+	return &events{ch: make(chan Event, buffer)}
+}
+
+// emit sends e without blocking, dropping it if the channel is full.
+func (ev *events) emit(e Event) {
+	e.Time = time.Now()
+
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	if ev.closed {
+		return
+	}
+	select {
+	case ev.ch <- e:
+	default:
+	}
+}
+
+// close closes the channel. Later events are discarded.
+func (ev *events) close() {
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+	if !ev.closed {
+		ev.closed = true
+		close(ev.ch)
+	}
+}
+
+// Events returns the channel on which a Group created with WithEvents
+// delivers its lifecycle events, or nil for any other Group. The channel is
+// closed when Wait returns.
+func (lg *Group) Events() <-chan Event {
+	if lg.events == nil {
+		return nil
+	}
+	return lg.events.ch
+}
+
+// emit delivers e if events are enabled.
+func (lg *Group) emit(e Event) {
+	if lg.events != nil {
+		e.Labels = lg.labels
+		lg.events.emit(e)
+	}
+}
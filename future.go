@@ -0,0 +1,256 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// A Future is the eventual result of a function run on a Group by GoValue.
+type Future[T any] struct {
+	lg     *Group
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	value  T
+	err    error
+}
+
+// GoValue is like GoContext, but returns a Future for the value computed by
+// f. The Context passed to f is canceled when the Group's is, or when All or
+// Any no longer need the value.
+//
+// Unlike Go, an error returned by f, or one that prevents it from starting,
+// is delivered by the Future rather than canceling the Group, and is not
+// returned by Wait.
+func GoValue[T any](lg *Group, f func(ctx context.Context) (T, error)) *Future[T] {
+	fut := newFuture[T](lg)
+	t := lg.newTask("")
+	if err := lg.acquire(fut.ctx, &t); err != nil {
+		var zero T
+		lg.settleTask(t, time.Time{}, err)
+		fut.resolve(zero, err)
+		return fut
+	}
+	lg.spawn(func() error {
+		fut.call(t, f)
+		return nil
+	})
+	return fut
+}
+
+// Then returns a Future for the result of calling fn with the value of fut,
+// as a subtask of the same Group, once fut has resolved successfully. If fut
+// fails, fn is not called and the returned Future fails with the same error.
+// Then never blocks; the continuation waits for a slot only once fut is
+// resolved.
+func Then[T, U any](fut *Future[T], fn func(ctx context.Context, v T) (U, error)) *Future[U] {
+	return continueWith(fut,
+		func(err error) bool { return err == nil },
+		func(ctx context.Context) (U, error) { return fn(ctx, fut.value) },
+		func() (U, error) {
+			var zero U
+			return zero, fut.err
+		})
+}
+
+// Catch returns a Future that resolves like fut if it succeeds, or otherwise
+// to the result of calling fn with its error, as a subtask of the same Group,
+// e.g. to fall back to a default value. Like Then, it never blocks.
+func (fut *Future[T]) Catch(fn func(ctx context.Context, err error) (T, error)) *Future[T] {
+	return continueWith(fut,
+		func(err error) bool { return err != nil },
+		func(ctx context.Context) (T, error) { return fn(ctx, fut.err) },
+		func() (T, error) { return fut.value, nil })
+}
+
+// All returns a Future that resolves to the values of the given futures, in
+// the same order, once all of them have succeeded. As soon as one fails, the
+// returned Future fails with its error and the others are canceled. Given no
+// futures, All returns one that has already resolved to an empty slice.
+func All[T any](futures ...*Future[T]) *Future[[]T] {
+	if len(futures) == 0 {
+		return resolved([]T{}, nil)
+	}
+	all := newFuture[[]T](futures[0].lg)
+	all.lg.spawn(func() error {
+		done := await(futures)
+		values := make([]T, len(futures))
+		for range futures {
+			select {
+			case i := <-done:
+				if err := futures[i].err; err != nil {
+					cancelAll(futures)
+					all.resolve(nil, err)
+					return nil
+				}
+				values[i] = futures[i].value
+			case <-all.ctx.Done():
+				cancelAll(futures)
+				all.resolve(nil, all.ctx.Err())
+				return nil
+			}
+		}
+		all.resolve(values, nil)
+		return nil
+	})
+	return all
+}
+
+// Any returns a Future that resolves to the value of the first of the given
+// futures to succeed, canceling the others. If all of them fail, it fails
+// with their errors joined with errors.Join, in the order the futures were
+// given. Given no futures, Any returns one that has already failed with
+// ErrNoFutures.
+func Any[T any](futures ...*Future[T]) *Future[T] {
+	if len(futures) == 0 {
+		var zero T
+		return resolved(zero, ErrNoFutures)
+	}
+	first := newFuture[T](futures[0].lg)
+	first.lg.spawn(func() error {
+		done := await(futures)
+		errs := make([]error, len(futures))
+		for range futures {
+			select {
+			case i := <-done:
+				if errs[i] = futures[i].err; errs[i] == nil {
+					cancelAll(futures)
+					first.resolve(futures[i].value, nil)
+					return nil
+				}
+			case <-first.ctx.Done():
+				var zero T
+				cancelAll(futures)
+				first.resolve(zero, first.ctx.Err())
+				return nil
+			}
+		}
+		var zero T
+		first.resolve(zero, errors.Join(errs...))
+		return nil
+	})
+	return first
+}
+
+// await returns a channel on which the index of each of the given futures is
+// sent as it is resolved.
+func await[T any](futures []*Future[T]) <-chan int {
+	done := make(chan int, len(futures))
+	for i, fut := range futures {
+		i, fut := i, fut
+		go func() {
+			<-fut.done
+			done <- i
+		}()
+	}
+	return done
+}
+
+// cancelAll cancels the Context of each of the given futures, so that those
+// not yet resolved stop waiting for a slot or are asked to stop running.
+func cancelAll[T any](futures []*Future[T]) {
+	for _, fut := range futures {
+		fut.cancel()
+	}
+}
+
+// continueWith returns a Future that, once fut is resolved, resolves to the
+// result of calling f as a subtask of fut's Group if run reports that it
+// should for the error fut resolved to, or to the result of pass otherwise.
+// If fut belongs to no Group, f is called on a goroutine of its own.
+func continueWith[T, U any](fut *Future[T], run func(err error) bool, f func(ctx context.Context) (U, error), pass func() (U, error)) *Future[U] {
+	lg := fut.lg
+	next := newFuture[U](lg)
+	if lg == nil {
+		// fut has already resolved, and there is no limit to run f under.
+		go func() {
+			if run(fut.err) {
+				next.resolve(f(next.ctx))
+			} else {
+				next.resolve(pass())
+			}
+		}()
+		return next
+	}
+	lg.spawn(func() error {
+		select {
+		case <-fut.done:
+		case <-next.ctx.Done():
+			var zero U
+			next.resolve(zero, next.ctx.Err())
+			return nil
+		}
+		if !run(fut.err) {
+			next.resolve(pass())
+			return nil
+		}
+
+		t := lg.newTask("")
+		if err := lg.acquire(next.ctx, &t); err != nil {
+			var zero U
+			lg.settleTask(t, time.Time{}, err)
+			next.resolve(zero, err)
+			return nil
+		}
+		next.call(t, f)
+		return nil
+	})
+	return next
+}
+
+// call runs f on behalf of t, which holds a slot, and resolves the Future
+// with its result.
+func (fut *Future[T]) call(t task, f func(ctx context.Context) (T, error)) {
+	defer fut.lg.release(t)
+
+	start := fut.lg.startTask(t)
+	done := fut.lg.account(t)
+	v, err := f(fut.lg.taskContext(fut.ctx, t))
+	done()
+	fut.lg.settleTask(t, start, err)
+	fut.resolve(v, err)
+}
+
+// newFuture returns an unresolved Future for a subtask of lg, which may be
+// nil for a Future that belongs to no Group.
+func newFuture[T any](lg *Group) *Future[T] {
+	parent := context.Background()
+	if lg != nil {
+		parent = lg.ctx
+	}
+	ctx, cancel := context.WithCancel(parent)
+	return &Future[T]{lg: lg, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// resolved returns a Future, belonging to no Group, that has already
+// resolved to v and err.
+func resolved[T any](v T, err error) *Future[T] {
+	fut := newFuture[T](nil)
+	fut.resolve(v, err)
+	return fut
+}
+
+// resolve sets the outcome of the Future. It must be called exactly once.
+func (fut *Future[T]) resolve(v T, err error) {
+	fut.value, fut.err = v, err
+	fut.cancel()
+	close(fut.done)
+}
+
+// Done returns a channel that is closed once the Future is resolved.
+func (fut *Future[T]) Done() <-chan struct{} {
+	return fut.done
+}
+
+// Result blocks until the Future is resolved, then returns the value and
+// error from its function. If ctx is done first, Result returns ctx's error.
+func (fut *Future[T]) Result(ctx context.Context) (T, error) {
+	select {
+	case <-fut.done:
+		return fut.value, fut.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,231 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// resultOf returns the outcome of fut, failing the test if it does not
+// resolve in time.
+func resultOf[T any](t *testing.T, fut *Future[T]) (T, error) {
+	t.Helper()
+
+	select {
+	case <-fut.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Future did not resolve")
+	}
+	return fut.Result(context.Background())
+}
+
+func TestGoValue(t *testing.T) {
+	errBad := errors.New("bad")
+	lg, _ := WithContext(context.Background(), 2)
+
+	ok := GoValue(lg, func(context.Context) (int, error) { return 42, nil })
+	bad := GoValue(lg, func(context.Context) (int, error) { return 0, errBad })
+
+	if v, err := resultOf(t, ok); v != 42 || err != nil {
+		t.Errorf("Result() = %v, %v, want 42, nil", v, err)
+	}
+	if _, err := resultOf(t, bad); !errors.Is(err, errBad) {
+		t.Errorf("Result() error = %v, want %v", err, errBad)
+	}
+
+	// The failure belongs to the Future alone.
+	if err := lg.Wait(); err != nil {
+		t.Errorf("Wait() = %v, want nil", err)
+	}
+}
+
+func TestGoValueResultContext(t *testing.T) {
+	lg, _ := WithContext(context.Background(), 1)
+	release := make(chan struct{})
+	fut := GoValue(lg, func(context.Context) (int, error) {
+		<-release
+		return 1, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fut.Result(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Result() error = %v, want context.Canceled", err)
+	}
+	close(release)
+	lg.Wait()
+}
+
+func TestGoValueGroupCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lg, _ := WithContext(ctx, 1)
+	fut := GoValue(lg, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	cancel()
+	if _, err := resultOf(t, fut); !errors.Is(err, context.Canceled) {
+		t.Errorf("Result() error = %v, want context.Canceled", err)
+	}
+	lg.Wait()
+}
+
+func TestThen(t *testing.T) {
+	errBad := errors.New("bad")
+	lg, _ := WithContext(context.Background(), 1)
+
+	ok := GoValue(lg, func(context.Context) (int, error) { return 2, nil })
+	formatted := Then(ok, func(_ context.Context, v int) (string, error) {
+		return strconv.Itoa(v * 2), nil
+	})
+	if v, err := resultOf(t, formatted); v != "4" || err != nil {
+		t.Errorf("Then Result() = %q, %v, want \"4\", nil", v, err)
+	}
+
+	called := false
+	bad := GoValue(lg, func(context.Context) (int, error) { return 0, errBad })
+	skipped := Then(bad, func(context.Context, int) (int, error) {
+		called = true
+		return 1, nil
+	})
+	if _, err := resultOf(t, skipped); !errors.Is(err, errBad) {
+		t.Errorf("Then Result() error = %v, want %v", err, errBad)
+	}
+	if called {
+		t.Error("Then called fn for a failed Future")
+	}
+	if err := lg.Wait(); err != nil {
+		t.Errorf("Wait() = %v, want nil", err)
+	}
+}
+
+func TestCatch(t *testing.T) {
+	errBad := errors.New("bad")
+	lg, _ := WithContext(context.Background(), 1)
+
+	bad := GoValue(lg, func(context.Context) (int, error) { return 0, errBad })
+	recovered := bad.Catch(func(_ context.Context, err error) (int, error) {
+		if !errors.Is(err, errBad) {
+			t.Errorf("Catch got %v, want %v", err, errBad)
+		}
+		return 7, nil
+	})
+	if v, err := resultOf(t, recovered); v != 7 || err != nil {
+		t.Errorf("Catch Result() = %v, %v, want 7, nil", v, err)
+	}
+
+	ok := GoValue(lg, func(context.Context) (int, error) { return 3, nil })
+	passed := ok.Catch(func(context.Context, error) (int, error) {
+		t.Error("Catch called fn for a successful Future")
+		return 0, nil
+	})
+	if v, err := resultOf(t, passed); v != 3 || err != nil {
+		t.Errorf("Catch Result() = %v, %v, want 3, nil", v, err)
+	}
+	lg.Wait()
+}
+
+func TestAll(t *testing.T) {
+	lg, _ := WithContext(context.Background(), 3)
+	futures := make([]*Future[int], 5)
+	for i := range futures {
+		futures[i] = GoValue(lg, func(context.Context) (int, error) {
+			// Later futures resolve first.
+			time.Sleep(time.Duration(5-i) * time.Millisecond)
+			return i, nil
+		})
+	}
+
+	v, err := resultOf(t, All(futures...))
+	if err != nil {
+		t.Fatalf("All Result() error = %v, want nil", err)
+	}
+	for i := range futures {
+		if v[i] != i {
+			t.Fatalf("All Result() = %v, want [0 1 2 3 4]", v)
+		}
+	}
+	lg.Wait()
+}
+
+func TestAllFailure(t *testing.T) {
+	errBad := errors.New("bad")
+	lg, _ := WithContext(context.Background(), 2)
+
+	slow := GoValue(lg, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	bad := GoValue(lg, func(context.Context) (int, error) { return 0, errBad })
+
+	if _, err := resultOf(t, All(slow, bad)); !errors.Is(err, errBad) {
+		t.Errorf("All Result() error = %v, want %v", err, errBad)
+	}
+	// The failure cancels the futures still running.
+	if _, err := resultOf(t, slow); !errors.Is(err, context.Canceled) {
+		t.Errorf("Result() of the other future = %v, want context.Canceled", err)
+	}
+	lg.Wait()
+}
+
+func TestAny(t *testing.T) {
+	errBad := errors.New("bad")
+	lg, _ := WithContext(context.Background(), 3)
+
+	slow := GoValue(lg, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	bad := GoValue(lg, func(context.Context) (int, error) { return 0, errBad })
+	ok := GoValue(lg, func(context.Context) (int, error) {
+		<-bad.Done()
+		return 2, nil
+	})
+
+	if v, err := resultOf(t, Any(slow, bad, ok)); v != 2 || err != nil {
+		t.Errorf("Any Result() = %v, %v, want 2, nil", v, err)
+	}
+	if _, err := resultOf(t, slow); !errors.Is(err, context.Canceled) {
+		t.Errorf("Result() of the other future = %v, want context.Canceled", err)
+	}
+	lg.Wait()
+}
+
+func TestAnyFailure(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	lg, _ := WithContext(context.Background(), 2)
+
+	a := GoValue(lg, func(context.Context) (int, error) { return 0, errA })
+	b := GoValue(lg, func(context.Context) (int, error) { return 0, errB })
+
+	_, err := resultOf(t, Any(a, b))
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Any Result() error = %v, want both %v and %v", err, errA, errB)
+	}
+	if want := "a\nb"; err == nil || err.Error() != want {
+		t.Errorf("Any Result() error = %q, want %q", err, want)
+	}
+	lg.Wait()
+}
+
+func TestNoFutures(t *testing.T) {
+	if v, err := resultOf(t, All[int]()); len(v) != 0 || v == nil || err != nil {
+		t.Errorf("All() Result() = %#v, %v, want []int{}, nil", v, err)
+	}
+	if _, err := resultOf(t, Any[int]()); !errors.Is(err, ErrNoFutures) {
+		t.Errorf("Any() Result() error = %v, want ErrNoFutures", err)
+	}
+}
+
+func TestThenOfResolved(t *testing.T) {
+	// Futures that belong to no Group can still be chained.
+	next := Then(All[int](), func(_ context.Context, v []int) (int, error) {
+		return len(v), nil
+	})
+	if v, err := resultOf(t, next); v != 0 || err != nil {
+		t.Errorf("Result() = %v, %v, want 0, nil", v, err)
+	}
+}
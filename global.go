@@ -0,0 +1,61 @@
+package limitgroup
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+)
+
+// global is the process-wide limiter shared by every Group. It is only
+// consulted while globalEnabled is non-zero.
+var (
+	global        = newLimiter(math.MaxInt64)
+	globalEnabled int32
+)
+
+// SetGlobalLimit sets a process-wide ceiling on the number of subtasks
+// running across all Groups. Every Group acquires a slot from the global
+// limit, after acquiring one of its own, before starting a subtask.
+//
+// A limit less than or equal to zero, the default, removes the ceiling.
+// Changes only affect slots acquired afterwards.
+func SetGlobalLimit(limit int64) {
+	if limit <= 0 {
+		atomic.StoreInt32(&globalEnabled, 0)
+		return
+	}
+	global.resize(limit)
+	atomic.StoreInt32(&globalEnabled, 1)
+}
+
+// GlobalLimit returns the process-wide ceiling set by SetGlobalLimit, or zero
+// if there is none.
+func GlobalLimit() int64 {
+	if atomic.LoadInt32(&globalEnabled) == 0 {
+		return 0
+	}
+	return global.limit()
+}
+
+// acquireGlobal acquires n slots from the global limit, if there is one, and
+// reports whether they must later be released.
+func acquireGlobal(ctx context.Context, n int64) (bool, error) {
+	if atomic.LoadInt32(&globalEnabled) == 0 {
+		return false, nil
+	}
+	if err := global.acquire(ctx, "", n); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// tryAcquireGlobal is like acquireGlobal, but fails rather than blocking.
+func tryAcquireGlobal(n int64) (held, ok bool) {
+	if atomic.LoadInt32(&globalEnabled) == 0 {
+		return false, true
+	}
+	if !global.tryAcquire("", n) {
+		return false, false
+	}
+	return true, true
+}
@@ -1,5 +1,5 @@
 module github.com/code-willing/go-limitgroup
 
-go 1.12
+go 1.23
 
-require golang.org/x/sync v0.0.0-20190423024810-112230192c58
+require golang.org/x/sync v0.1.0
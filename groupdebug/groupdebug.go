@@ -0,0 +1,155 @@
+// Package groupdebug provides an http.Handler for inspecting and tuning
+// limitgroup.Groups in a running process, as an operational escape hatch.
+//
+// A GET request renders the stats and running subtasks of every registered
+// Group as plain text. A POST request with the form values "group" and
+// "limit" changes the limit of the named Group, as if by SetLimit.
+package groupdebug
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	limitgroup "github.com/code-willing/go-limitgroup"
+)
+
+// maxRunningListed is the number of running subtasks listed for each Group.
+const maxRunningListed = 20
+
+// A Registry is a set of named Groups to expose, and the http.Handler that
+// exposes them. The zero Registry is empty and ready to use.
+type Registry struct {
+	mu     sync.Mutex
+	groups map[string]*limitgroup.Group
+}
+
+// DefaultRegistry is the Registry used by Register, Unregister, and Handler.
+var DefaultRegistry = new(Registry)
+
+// Register adds lg to DefaultRegistry under name.
+func Register(name string, lg *limitgroup.Group) {
+	DefaultRegistry.Register(name, lg)
+}
+
+// Unregister removes the Group registered under name from DefaultRegistry.
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+// Handler returns an http.Handler that serves DefaultRegistry.
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+// Register adds lg to the Registry under name, replacing any Group already
+// registered under it. Groups should be unregistered once Wait has returned.
+func (r *Registry) Register(name string, lg *limitgroup.Group) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.groups == nil {
+		r.groups = make(map[string]*limitgroup.Group)
+	}
+	r.groups[name] = lg
+}
+
+// Unregister removes the Group registered under name, if any.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.groups, name)
+}
+
+// lookup returns the Group registered under name, or nil.
+func (r *Registry) lookup(name string) *limitgroup.Group {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.groups[name]
+}
+
+// ServeHTTP renders the registered Groups on GET and changes a Group's limit
+// on POST.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		r.render(w)
+	case http.MethodPost:
+		r.setLimit(w, req)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// render writes the stats and running subtasks of every registered Group, in
+// order of name.
+func (r *Registry) render(w http.ResponseWriter) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.groups))
+	groups := make(map[string]*limitgroup.Group, len(r.groups))
+	for name, lg := range r.groups {
+		names = append(names, name)
+		groups[name] = lg
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	now := time.Now()
+	for _, name := range names {
+		lg := groups[name]
+		st := lg.Stats()
+		fmt.Fprintf(w, "group %q: limit %d, in flight %d, waiting %d, total wait %v\n",
+			name, st.Limit, st.InFlight, st.Waiting, st.WaitTime.Round(time.Millisecond))
+		if len(st.Labels) > 0 {
+			keys := make([]string, 0, len(st.Labels))
+			for k := range st.Labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Fprint(w, "\tlabels:")
+			for _, k := range keys {
+				fmt.Fprintf(w, " %s=%s", k, st.Labels[k])
+			}
+			fmt.Fprintln(w)
+		}
+
+		running := lg.Running()
+		for i, ti := range running {
+			if i == maxRunningListed {
+				fmt.Fprintf(w, "\t... and %d more\n", len(running)-i)
+				break
+			}
+			label := ti.Name
+			if label == "" {
+				label = "#" + strconv.Itoa(ti.ID)
+			}
+			fmt.Fprintf(w, "\t%s running for %v\n", label, now.Sub(ti.Start).Round(time.Millisecond))
+		}
+	}
+}
+
+// setLimit changes the limit of the Group named by the request's "group"
+// form value to its "limit" form value.
+func (r *Registry) setLimit(w http.ResponseWriter, req *http.Request) {
+	name := req.FormValue("group")
+	lg := r.lookup(name)
+	if lg == nil {
+		http.Error(w, fmt.Sprintf("no group %q", name), http.StatusNotFound)
+		return
+	}
+	limit, err := strconv.Atoi(req.FormValue("limit"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	lg.SetLimit(limit)
+	fmt.Fprintf(w, "group %q: limit %d\n", name, lg.Limit())
+}
@@ -0,0 +1,131 @@
+package groupdebug
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	limitgroup "github.com/code-willing/go-limitgroup"
+)
+
+func TestRender(t *testing.T) {
+	var reg Registry
+	a, _ := limitgroup.WithContext(context.Background(), 3, limitgroup.WithLabels(map[string]string{"job": "backfill"}))
+	b, _ := limitgroup.WithContext(context.Background(), 1)
+	reg.Register("b", b)
+	reg.Register("a", a)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	a.GoNamed("fetch", func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer func() {
+		close(release)
+		a.Wait()
+		b.Wait()
+	}()
+
+	srv := httptest.NewServer(&reg)
+	defer srv.Close()
+
+	status, body := get(t, srv.URL)
+	if status != http.StatusOK {
+		t.Fatalf("GET status = %d, want 200", status)
+	}
+	for _, want := range []string{
+		`group "a": limit 3, in flight 1,`,
+		"\tlabels: job=backfill\n",
+		"\tfetch running for ",
+		`group "b": limit 1, in flight 0,`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET body = %q, want it to contain %q", body, want)
+		}
+	}
+	if strings.Index(body, `group "a"`) > strings.Index(body, `group "b"`) {
+		t.Errorf("GET body = %q, want groups in order of name", body)
+	}
+
+	reg.Unregister("b")
+	if _, body := get(t, srv.URL); strings.Contains(body, `group "b"`) {
+		t.Errorf("GET body = %q after Unregister, want no group b", body)
+	}
+}
+
+func TestSetLimit(t *testing.T) {
+	var reg Registry
+	lg, _ := limitgroup.WithContext(context.Background(), 2)
+	defer lg.Wait()
+	reg.Register("g", lg)
+
+	srv := httptest.NewServer(&reg)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantLimit  int64
+	}{
+		{"change limit", url.Values{"group": {"g"}, "limit": {"5"}}, http.StatusOK, 5},
+		{"unknown group", url.Values{"group": {"h"}, "limit": {"1"}}, http.StatusNotFound, 5},
+		{"invalid limit", url.Values{"group": {"g"}, "limit": {"many"}}, http.StatusBadRequest, 5},
+		{"lower limit", url.Values{"group": {"g"}, "limit": {"1"}}, http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.PostForm(srv.URL, tt.form)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("POST status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := lg.Limit(); got != tt.wantLimit {
+				t.Errorf("Limit() = %d, want %d", got, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	srv := httptest.NewServer(new(Registry))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE status = %d, want 405", resp.StatusCode)
+	}
+	if allow := resp.Header.Get("Allow"); allow != "GET, HEAD, POST" {
+		t.Errorf("Allow = %q, want \"GET, HEAD, POST\"", allow)
+	}
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
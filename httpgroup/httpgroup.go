@@ -0,0 +1,179 @@
+// Package httpgroup executes HTTP requests concurrently with bounded
+// concurrency, per-request timeouts, and retries of transient failures,
+// built on limitgroup.Group.
+package httpgroup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	limitgroup "github.com/code-willing/go-limitgroup"
+)
+
+// Options configures Do. The zero Options uses http.DefaultClient, the
+// default limit, no timeout, and no retries.
+type Options struct {
+	// Client sends the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// Limit is the maximum number of requests in flight at once, interpreted
+	// as for limitgroup.WithContext.
+	Limit int64
+	// Timeout, if non-zero, bounds each attempt at a request, including
+	// reading the response body.
+	Timeout time.Duration
+	// MaxRetries is the number of times a request is retried after a failure
+	// classified as retryable by Retryable.
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubling for each retry
+	// after that. A Retry-After header in the response takes precedence.
+	Backoff time.Duration
+	// Retryable classifies the outcome of an attempt. If nil,
+	// DefaultRetryable is used.
+	Retryable func(resp *http.Response, err error) bool
+}
+
+// Result is the outcome of a single request. A response with a non-2xx status
+// is not treated as an error; check StatusCode.
+type Result struct {
+	Request    *http.Request
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Attempts is the number of times the request was sent.
+	Attempts int
+	// Err is the error from the final attempt, if it failed.
+	Err error
+}
+
+// DefaultRetryable reports whether an attempt should be retried: transport
+// errors, other than the request's context being done, and responses with a
+// status of 429 Too Many Requests or 5xx are retryable.
+func DefaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// Do sends every request concurrently, with at most opts.Limit in flight at
+// once, and returns their results in the same order as reqs. A failed request
+// does not affect the others; if ctx is done, requests that have not yet been
+// sent fail with its error.
+//
+// Requests with a body are only retried if they have a GetBody function, as
+// set by http.NewRequest for common body types. Such requests are sent with
+// bodies obtained from GetBody and can be reused; the body of any other
+// request is consumed and closed.
+func Do(ctx context.Context, reqs []*http.Request, opts Options) []Result {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = DefaultRetryable
+	}
+
+	results := make([]Result, len(reqs))
+	lg, gctx := limitgroup.WithContext(ctx, opts.Limit)
+	for i, req := range reqs {
+		i, req := i, req
+		results[i].Request = req
+		lg.Go(func() error {
+			results[i] = send(gctx, req, opts)
+			return nil
+		})
+	}
+	lg.Wait()
+
+	for i := range results {
+		if results[i].Attempts == 0 && results[i].Err == nil {
+			results[i].Err = ctx.Err()
+		}
+	}
+	return results
+}
+
+// send sends req, retrying as allowed by opts.
+func send(ctx context.Context, req *http.Request, opts Options) Result {
+	res := Result{Request: req}
+	backoff := opts.Backoff
+	// Every attempt, including the first, sends a copy of the body from
+	// GetBody where possible, leaving the caller's request as it was.
+	r := *req
+	if r.GetBody != nil {
+		if err := rewind(&r); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+	for {
+		resp, err := attempt(ctx, &r, opts, &res)
+		res.Attempts++
+
+		retry := res.Attempts <= opts.MaxRetries && opts.Retryable(resp, err) && rewind(&r) == nil
+		if !retry {
+			return res
+		}
+
+		delay := backoff
+		if resp != nil {
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+				delay = time.Duration(secs) * time.Second
+			}
+		}
+		backoff *= 2
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			res.Err = ctx.Err()
+			return res
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends req once, recording the outcome in res. The returned response
+// has already had its body read and closed.
+func attempt(ctx context.Context, req *http.Request, opts Options, res *Result) (*http.Response, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	*res = Result{Request: res.Request, Attempts: res.Attempts}
+	resp, err := opts.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		res.Err = err
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res.StatusCode, res.Header = resp.StatusCode, resp.Header
+	if res.Body, err = io.ReadAll(resp.Body); err != nil {
+		res.Err = err
+		return nil, err
+	}
+	return resp, nil
+}
+
+// rewind prepares req to be sent again.
+func rewind(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody == nil {
+		return errors.New("httpgroup: request body cannot be rewound")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
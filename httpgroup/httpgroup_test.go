@@ -0,0 +1,215 @@
+package httpgroup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	var inFlight, peak int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	paths := []string{"/a", "/b", "/missing", "/c", "/d", "/e"}
+	reqs := make([]*http.Request, len(paths))
+	for i, p := range paths {
+		reqs[i] = mustRequest(t, http.MethodGet, srv.URL+p, nil)
+	}
+
+	results := Do(context.Background(), reqs, Options{Limit: 2})
+	for i, res := range results {
+		if res.Request != reqs[i] {
+			t.Errorf("result %d is for the wrong request", i)
+		}
+		if res.Err != nil {
+			t.Errorf("result %d: Err = %v, want nil", i, res.Err)
+			continue
+		}
+		if paths[i] == "/missing" {
+			if res.StatusCode != http.StatusNotFound {
+				t.Errorf("result %d: StatusCode = %d, want 404", i, res.StatusCode)
+			}
+		} else if res.StatusCode != http.StatusOK || string(res.Body) != paths[i] {
+			t.Errorf("result %d: %d %q, want 200 %q", i, res.StatusCode, res.Body, paths[i])
+		}
+		if res.Attempts != 1 {
+			t.Errorf("result %d: Attempts = %d, want 1", i, res.Attempts)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("%d requests in flight at once, want at most 2", peak)
+	}
+}
+
+func TestDoRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int // returned for successive attempts
+		maxRetries   int
+		retryAfter   string
+		wantStatus   int
+		wantAttempts int
+	}{
+		{
+			name:         "retried until success",
+			statuses:     []int{503, 500, 200},
+			maxRetries:   3,
+			wantStatus:   200,
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries exhausted",
+			statuses:     []int{503, 503, 503},
+			maxRetries:   1,
+			wantStatus:   503,
+			wantAttempts: 2,
+		},
+		{
+			name:         "not retryable",
+			statuses:     []int{400, 200},
+			maxRetries:   3,
+			wantStatus:   400,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Retry-After overrides backoff",
+			statuses:     []int{429, 200},
+			maxRetries:   1,
+			retryAfter:   "0",
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu     sync.Mutex
+				bodies []string
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				status := tt.statuses[len(bodies)]
+				bodies = append(bodies, string(body))
+				mu.Unlock()
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+			}))
+			defer srv.Close()
+
+			backoff := time.Millisecond
+			if tt.retryAfter != "" {
+				// Long enough to time the test out unless Retry-After wins.
+				backoff = time.Hour
+			}
+			req := mustRequest(t, http.MethodPost, srv.URL, strings.NewReader("payload"))
+			res := Do(context.Background(), []*http.Request{req}, Options{
+				MaxRetries: tt.maxRetries,
+				Backoff:    backoff,
+			})[0]
+
+			if res.Err != nil {
+				t.Fatalf("Err = %v, want nil", res.Err)
+			}
+			if res.StatusCode != tt.wantStatus || res.Attempts != tt.wantAttempts {
+				t.Errorf("StatusCode, Attempts = %d, %d, want %d, %d", res.StatusCode, res.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			for i, b := range bodies {
+				if b != "payload" {
+					t.Errorf("attempt %d sent body %q, want \"payload\"", i, b)
+				}
+			}
+
+			// The caller's request is left as it was.
+			if body, _ := io.ReadAll(req.Body); string(body) != "payload" {
+				t.Errorf("caller's body = %q after Do, want \"payload\"", body)
+			}
+		})
+	}
+}
+
+func TestDoUnrewindableBody(t *testing.T) {
+	var attempts int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// Hiding the reader's type leaves the request without GetBody.
+	req := mustRequest(t, http.MethodPost, srv.URL, struct{ io.Reader }{strings.NewReader("x")})
+	res := Do(context.Background(), []*http.Request{req}, Options{MaxRetries: 3, Backoff: time.Millisecond})[0]
+	if res.Attempts != 1 || attempts != 1 {
+		t.Errorf("sent %d times, Attempts = %d, want 1", attempts, res.Attempts)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("request sent after ctx was canceled")
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := Do(ctx, []*http.Request{mustRequest(t, http.MethodGet, srv.URL, nil)}, Options{})[0]
+	if !errors.Is(res.Err, context.Canceled) {
+		t.Errorf("Err = %v, want context.Canceled", res.Err)
+	}
+}
+
+func TestDoTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	res := Do(context.Background(), []*http.Request{mustRequest(t, http.MethodGet, srv.URL, nil)}, Options{
+		Timeout:    10 * time.Millisecond,
+		MaxRetries: 1,
+		Backoff:    time.Millisecond,
+	})[0]
+	if !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Errorf("Err = %v, want context.DeadlineExceeded", res.Err)
+	}
+	if res.Attempts != 2 {
+		t.Errorf("Attempts = %d, want 2", res.Attempts)
+	}
+}
+
+func mustRequest(t *testing.T, method, url string, body io.Reader) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
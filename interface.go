@@ -0,0 +1,17 @@
+package limitgroup
+
+import "golang.org/x/sync/errgroup"
+
+// Interface is the method set shared by Group and errgroup.Group, so that
+// code which accepts an Interface can be handed either.
+type Interface interface {
+	Go(f func() error)
+	TryGo(f func() error) bool
+	SetLimit(n int)
+	Wait() error
+}
+
+var (
+	_ Interface = (*Group)(nil)
+	_ Interface = (*errgroup.Group)(nil)
+)
@@ -0,0 +1,49 @@
+package limitgroup
+
+import "sync"
+
+// journal is a fixed-size ring of the most recently completed subtasks.
+type journal struct {
+	mu      sync.Mutex
+	records []TaskResult
+	next    int
+	full    bool
+}
+
+func newJournal(size int) *journal {
+	return &journal{records: make([]TaskResult, size)}
+}
+
+// add records res, evicting the oldest record if the journal is full.
+func (j *journal) add(res TaskResult) {
+	j.mu.Lock()
+	j.records[j.next] = res
+	j.next = (j.next + 1) % len(j.records)
+	if j.next == 0 {
+		j.full = true
+	}
+	j.mu.Unlock()
+}
+
+// snapshot returns the records in the journal, oldest first.
+func (j *journal) snapshot() []TaskResult {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.full {
+		return append([]TaskResult(nil), j.records[:j.next]...)
+	}
+	out := make([]TaskResult, 0, len(j.records))
+	out = append(out, j.records[j.next:]...)
+	return append(out, j.records[:j.next]...)
+}
+
+// Journal returns the records of the most recently completed subtasks,
+// oldest first, for a Group created with WithJournal, or nil for any other
+// Group. Subtasks that were shed or could not be started are included.
+func (lg *Group) Journal() []TaskResult {
+	if lg.journal == nil {
+		return nil
+	}
+	return lg.journal.snapshot()
+}
@@ -0,0 +1,121 @@
+package limitgroup
+
+import (
+	"context"
+	"sync"
+)
+
+// MapKeyed calls fn for every key/value pair in the given map, with at most
+// limit calls in flight at once, and returns the results of the successful
+// calls and the errors of the failed ones, each keyed by input key.
+//
+// A failing call does not cancel the others; the context passed to fn is
+// only canceled when ctx is, in which case keys that had not yet started
+// report the context's error. As with WithContext, a limit less than or equal
+// to zero selects the default limit.
+func MapKeyed[K comparable, V, R any](ctx context.Context, limit int64, in map[K]V, fn func(context.Context, K, V) (R, error)) (map[K]R, map[K]error) {
+	return MapKeyedCost(ctx, limit, in, nil, fn)
+}
+
+// MapKeyedCost is like MapKeyed, but each call occupies as many slots as
+// cost returns for its value, as with GoCost, so that limit caps the total
+// cost of the calls in flight, e.g. bytes rather than a number of values. A
+// nil cost counts every value as one.
+func MapKeyedCost[K comparable, V, R any](ctx context.Context, limit int64, in map[K]V, cost func(V) int64, fn func(context.Context, K, V) (R, error)) (map[K]R, map[K]error) {
+	var (
+		mu      sync.Mutex
+		results = make(map[K]R, len(in))
+		errs    = make(map[K]error)
+	)
+
+	lg, ctx := WithContext(ctx, limit)
+	for k, v := range in {
+		k, v := k, v
+		t := lg.newTask("")
+		if cost != nil {
+			t.cost = cost(v)
+		}
+		if err := lg.acquire(ctx, &t); err != nil {
+			// Keys that never got to run report why.
+			mu.Lock()
+			errs[k] = err
+			mu.Unlock()
+			continue
+		}
+		lg.run(t, func() error {
+			r, err := fn(ctx, k, v)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[k] = err
+			} else {
+				results[k] = r
+			}
+			return nil
+		})
+	}
+	lg.Wait()
+
+	return results, errs
+}
+
+// Keyed runs subtasks on a Group, each identified by a key, and reports the
+// outcome of every key separately, so that callers can retry exactly the keys
+// that failed.
+//
+// Unlike with Go, a failing subtask does not cancel the Group.
+type Keyed[K comparable] struct {
+	lg *Group
+
+	mu   sync.Mutex
+	errs map[K]error
+}
+
+// NewKeyed returns a Keyed that runs its subtasks on the given Group.
+func NewKeyed[K comparable](lg *Group) *Keyed[K] {
+	return &Keyed[K]{lg: lg, errs: make(map[K]error)}
+}
+
+// Go calls the given function for key in a new goroutine, blocking until a
+// slot is available. If a slot cannot be acquired, e.g. because the Group's
+// Context is done, key's entry records why.
+func (k *Keyed[K]) Go(key K, f func() error) {
+	t := k.lg.newTask("")
+	if err := k.lg.acquire(k.lg.ctx, &t); err != nil {
+		k.lg.shedTask(t, err)
+		k.record(key, err)
+		return
+	}
+	k.lg.run(t, func() error {
+		k.record(key, f())
+		return nil
+	})
+}
+
+// record sets the outcome of key. A key submitted more than once keeps the
+// error of any of its calls that failed.
+func (k *Keyed[K]) record(key K, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if prev, ok := k.errs[key]; !ok || prev == nil {
+		k.errs[key] = err
+	}
+}
+
+// Wait blocks until all subtasks have returned, then returns the outcome of
+// every key submitted: nil for keys that succeeded and the error for those
+// that failed or could not be started.
+func (k *Keyed[K]) Wait() map[K]error {
+	k.lg.Wait()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	errs := make(map[K]error, len(k.errs))
+	for key, err := range k.errs {
+		errs[key] = err
+	}
+	return errs
+}
@@ -0,0 +1,140 @@
+package limitgroup
+
+import (
+	"math"
+	"runtime"
+	"time"
+)
+
+// procsPollInterval is how often a Group created with WithGOMAXPROCS checks
+// for changes to runtime.GOMAXPROCS.
+const procsPollInterval = time.Second
+
+// defaultLimit returns the limit used when none is specified.
+func defaultLimit(useProcs bool) int64 {
+	if useProcs {
+		return int64(runtime.GOMAXPROCS(0) * 2)
+	}
+	return int64(runtime.NumCPU() * 2)
+}
+
+// trackGOMAXPROCS resizes the Group's limit whenever runtime.GOMAXPROCS
+// changes, until the Group's context is done or SetLimit is called.
+func (lg *Group) trackGOMAXPROCS() {
+	t := time.NewTicker(procsPollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-lg.ctx.Done():
+			return
+		case <-t.C:
+			lg.limitMu.Lock()
+			limitSet := lg.limitSet
+			limit := defaultLimit(true)
+			if !limitSet && limit != lg.target {
+				lg.target = limit
+				lg.applyLimit()
+			}
+			lg.limitMu.Unlock()
+			if limitSet {
+				return
+			}
+		}
+	}
+}
+
+// rampUp raises the ramp-up limit by one step every interval until it
+// reaches the target limit, or the Group's context is done.
+func (lg *Group) rampUp() {
+	t := time.NewTicker(lg.rampInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-lg.ctx.Done():
+			return
+		case <-t.C:
+			lg.limitMu.Lock()
+			lg.ramp += lg.rampStep
+			if lg.ramp >= lg.target {
+				lg.ramp = 0
+			}
+			done := lg.ramp == 0
+			lg.applyLimit()
+			lg.limitMu.Unlock()
+			if done {
+				return
+			}
+		}
+	}
+}
+
+// followSchedule sets the Group's limit from its schedule every interval,
+// until the Group's context is done or SetLimit is called.
+func (lg *Group) followSchedule() {
+	t := time.NewTicker(lg.scheduleEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-lg.ctx.Done():
+			return
+		case now := <-t.C:
+			lg.limitMu.Lock()
+			limitSet := lg.limitSet
+			if limit := lg.schedule(now); !limitSet && limit > 0 {
+				lg.target = limit
+				lg.applyLimit()
+			}
+			lg.limitMu.Unlock()
+			if limitSet {
+				return
+			}
+		}
+	}
+}
+
+// Limit returns the maximum level of concurrency for the Group. While the
+// Group is ramping up, this is the current ramp-up limit.
+func (lg *Group) Limit() int64 {
+	return lg.sem.limit()
+}
+
+// SetLimit changes the limit of the Group to n, as for errgroup.Group: a
+// negative value means no limit, and zero prevents any new subtasks from
+// starting. Unlike errgroup, the limit may be changed while subtasks are
+// running; subtasks over a reduced limit are allowed to finish.
+//
+// An explicit limit replaces one derived with WithGOMAXPROCS, which then
+// stops tracking GOMAXPROCS. During ramp-up, the new limit becomes the
+// target being ramped up to.
+func (lg *Group) SetLimit(n int) {
+	limit := int64(n)
+	if n < 0 {
+		limit = math.MaxInt64
+	}
+
+	lg.limitMu.Lock()
+	defer lg.limitMu.Unlock()
+	lg.limitSet = true
+	lg.target = limit
+	lg.applyLimit()
+}
+
+// effectiveLimit returns the target limit, capped by the ramp-up limit while
+// ramping up. lg.limitMu must be held, or the Group not yet shared.
+func (lg *Group) effectiveLimit() int64 {
+	if lg.ramp > 0 && lg.ramp < lg.target {
+		return lg.ramp
+	}
+	return lg.target
+}
+
+// applyLimit resizes the Group to its effective limit if that has changed.
+// lg.limitMu must be held.
+func (lg *Group) applyLimit() {
+	limit := lg.effectiveLimit()
+	if limit == lg.sem.limit() {
+		return
+	}
+	lg.sem.resize(limit)
+	lg.emit(Event{Kind: EventLimitChanged, TaskID: -1, Limit: limit})
+}
@@ -0,0 +1,373 @@
+package limitgroup
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter is a weighted semaphore, modelled on golang.org/x/sync/semaphore,
+// whose size can be changed while it is in use.
+//
+// Waiters are normally granted weight in FIFO order. When tenant weights are
+// set, contended weight is instead shared between tenants in proportion to
+// their weights. Waiters whose tenant is at its in-flight quota are passed
+// over until it has capacity again. Setting fifo disables both of these, so
+// that weight is always granted strictly in FIFO order.
+type limiter struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters list.List
+	fifo    bool
+	// waited is the total time spent waiting by waiters no longer queued.
+	waited time.Duration
+
+	// onChange, if set, is called without l.mu held after the weight held or
+	// the size changes.
+	onChange func()
+
+	// Burst capacity above size. credit is the number of burst slots that
+	// may currently be granted; it is replenished at a rate of burst per
+	// window, but only while the limiter is at or below its size.
+	burst  int64
+	window time.Duration
+	credit float64
+	last   time.Time
+
+	// Weighted fair queueing and quotas between tenants. held and queued
+	// track the weight currently granted to, and the number of waiters
+	// blocked for, each tenant; they are only maintained when weights or
+	// quotas are set.
+	weights map[string]float64
+	quotas  map[string]Quota
+	held    map[string]int64
+	queued  map[string]int
+}
+
+type waiter struct {
+	tenant string
+	n      int64
+	ready  chan<- struct{} // Closed when the limiter is acquired.
+	since  time.Time       // When the waiter was queued.
+}
+
+func newLimiter(size int64) *limiter {
+	return &limiter{size: size}
+}
+
+// setBurst allows up to extra weight beyond the size of the limiter to be
+// granted, drawing on a credit that is paid back over window.
+func (l *limiter) setBurst(extra int64, window time.Duration) {
+	l.mu.Lock()
+	l.burst, l.window = extra, window
+	l.credit, l.last = float64(extra), time.Now()
+	l.mu.Unlock()
+}
+
+// setFIFO enforces strict FIFO ordering of grants.
+func (l *limiter) setFIFO() {
+	l.mu.Lock()
+	l.fifo = true
+	l.mu.Unlock()
+}
+
+// setWeights enables weighted fair queueing between tenants. Tenants that
+// are missing from weights have a weight of 1.
+func (l *limiter) setWeights(weights map[string]float64) {
+	l.mu.Lock()
+	l.weights = make(map[string]float64, len(weights))
+	for tenant, w := range weights {
+		l.weights[tenant] = w
+	}
+	l.trackTenants()
+	l.mu.Unlock()
+}
+
+// setQuotas enables per-tenant quotas. Tenants that are missing from quotas
+// are unrestricted.
+func (l *limiter) setQuotas(quotas map[string]Quota) {
+	l.mu.Lock()
+	l.quotas = make(map[string]Quota, len(quotas))
+	for tenant, q := range quotas {
+		l.quotas[tenant] = q
+	}
+	l.trackTenants()
+	l.mu.Unlock()
+}
+
+// trackTenants starts tracking per-tenant usage. l.mu must be held.
+func (l *limiter) trackTenants() {
+	if l.held == nil {
+		l.held = make(map[string]int64)
+		l.queued = make(map[string]int)
+	}
+}
+
+// acquire acquires the limiter with a weight of n on behalf of tenant,
+// blocking until resources are available or ctx is done. On success, returns
+// nil. On failure, returns ctx.Err(), or ErrQuotaExceeded if the tenant may
+// not wait, and leaves the limiter unchanged.
+//
+// If ctx is already done, acquire may still succeed without blocking.
+func (l *limiter) acquire(ctx context.Context, tenant string, n int64) error {
+	l.mu.Lock()
+	if l.waiters.Len() == 0 && l.grant(tenant, n) {
+		l.mu.Unlock()
+		l.changed()
+		return nil
+	}
+
+	if q, ok := l.quotas[tenant]; ok {
+		if (q.MaxQueued > 0 && l.queued[tenant] >= q.MaxQueued) || (q.MaxInFlight > 0 && n > q.MaxInFlight) {
+			l.mu.Unlock()
+			return ErrQuotaExceeded
+		}
+	}
+
+	// Unlike semaphore.Weighted, a request larger than the current size is
+	// queued rather than failed outright since the size may grow later.
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(waiter{tenant: tenant, n: n, ready: ready, since: time.Now()})
+	if l.queued != nil {
+		l.queued[tenant]++
+	}
+	if l.quotas != nil && !l.fifo {
+		// The waiters ahead of us may all be held back by their quotas.
+		l.notifyWaiters()
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		err := ctx.Err()
+		l.mu.Lock()
+		select {
+		case <-ready:
+			// Acquired the limiter after we were canceled. Rather than trying to
+			// fix up the queue, just pretend we didn't notice the cancelation.
+			err = nil
+		default:
+			l.remove(elem)
+			// We may have been the waiter holding the others up, so notify
+			// them if there are tokens left.
+			if l.size > l.cur {
+				l.notifyWaiters()
+			}
+		}
+		l.mu.Unlock()
+		l.changed()
+		return err
+
+	case <-ready:
+		return nil
+	}
+}
+
+// tryAcquire acquires the limiter with a weight of n on behalf of tenant
+// without blocking. On success, returns true. On failure, returns false and
+// leaves the limiter unchanged.
+func (l *limiter) tryAcquire(tenant string, n int64) bool {
+	l.mu.Lock()
+	success := l.waiters.Len() == 0 && l.grant(tenant, n)
+	l.mu.Unlock()
+	if success {
+		l.changed()
+	}
+	return success
+}
+
+// release releases the limiter with a weight of n held by tenant.
+func (l *limiter) release(tenant string, n int64) {
+	l.mu.Lock()
+	l.replenish()
+	l.cur -= n
+	if l.cur < 0 {
+		l.mu.Unlock()
+		panic("limitgroup: released more than held")
+	}
+	if l.held != nil {
+		l.held[tenant] -= n
+		if l.held[tenant] <= 0 {
+			delete(l.held, tenant)
+		}
+	}
+	l.notifyWaiters()
+	l.mu.Unlock()
+	l.changed()
+}
+
+// resize changes the maximum combined weight of the limiter. Shrinking the
+// limiter never interrupts current holders; new acquisitions simply block
+// until enough weight has been released.
+func (l *limiter) resize(size int64) {
+	l.mu.Lock()
+	l.replenish()
+	l.size = size
+	l.notifyWaiters()
+	l.mu.Unlock()
+	l.changed()
+}
+
+// changed calls the onChange hook, if any. l.mu must not be held.
+func (l *limiter) changed() {
+	if l.onChange != nil {
+		l.onChange()
+	}
+}
+
+// limit returns the current size of the limiter.
+func (l *limiter) limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// usage returns the weight currently held and the size of the limiter.
+func (l *limiter) usage() (cur, size int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cur, l.size
+}
+
+// waiting returns the number of waiters currently queued and the total time
+// spent waiting by all waiters so far, including those still queued.
+func (l *limiter) waiting() (n int, waited time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	waited = l.waited
+	now := time.Now()
+	for e := l.waiters.Front(); e != nil; e = e.Next() {
+		waited += now.Sub(e.Value.(waiter).since)
+	}
+	return l.waiters.Len(), waited
+}
+
+// notifyWaiters wakes as many waiters as the available weight allows.
+// l.mu must be held.
+func (l *limiter) notifyWaiters() {
+	for {
+		next := l.nextWaiter()
+		if next == nil {
+			break // No more waiters blocked.
+		}
+
+		w := next.Value.(waiter)
+		if !l.grant(w.tenant, w.n) {
+			// Not enough tokens for the next waiter. Leave all remaining waiters
+			// blocked rather than letting smaller requests jump the queue and
+			// starve larger ones.
+			break
+		}
+
+		l.remove(next)
+		close(w.ready)
+	}
+}
+
+// remove removes a waiter from the queue. l.mu must be held.
+func (l *limiter) remove(e *list.Element) {
+	w := e.Value.(waiter)
+	if l.queued != nil {
+		l.queued[w.tenant]--
+		if l.queued[w.tenant] <= 0 {
+			delete(l.queued, w.tenant)
+		}
+	}
+	l.waited += time.Since(w.since)
+	l.waiters.Remove(e)
+}
+
+// nextWaiter returns the waiter that should be granted weight next: the
+// longest waiting one or, with weighted fair queueing, the longest waiting
+// one of the tenant furthest below its fair share. Unless strict FIFO
+// ordering is enforced, waiters of tenants at their in-flight quota are
+// skipped. l.mu must be held.
+func (l *limiter) nextWaiter() *list.Element {
+	front := l.waiters.Front()
+	if l.fifo || (l.weights == nil && l.quotas == nil) || front == nil {
+		return front
+	}
+
+	var (
+		best      *list.Element
+		bestShare float64
+		seen      = make(map[string]bool)
+	)
+	for e := front; e != nil; e = e.Next() {
+		w := e.Value.(waiter)
+		if seen[w.tenant] {
+			continue
+		}
+		seen[w.tenant] = true
+		if !l.withinQuota(w.tenant, w.n) {
+			continue
+		}
+		if l.weights == nil {
+			return e
+		}
+
+		weight, ok := l.weights[w.tenant]
+		if !ok || weight <= 0 {
+			weight = 1
+		}
+		share := float64(l.held[w.tenant]+w.n) / weight
+		if best == nil || share < bestShare {
+			best, bestShare = e, share
+		}
+	}
+	return best
+}
+
+// withinQuota reports whether tenant may be granted another n weight without
+// exceeding its in-flight quota. l.mu must be held.
+func (l *limiter) withinQuota(tenant string, n int64) bool {
+	q, ok := l.quotas[tenant]
+	return !ok || q.MaxInFlight <= 0 || l.held[tenant]+n <= q.MaxInFlight
+}
+
+// grant adds n to the weight held by tenant if its quota and the size, or
+// failing that the burst credit, allow it. l.mu must be held.
+func (l *limiter) grant(tenant string, n int64) bool {
+	if !l.withinQuota(tenant, n) {
+		return false
+	}
+	if l.size-l.cur < n {
+		if l.burst <= 0 || l.cur+n > l.size+l.burst {
+			return false
+		}
+		l.replenish()
+		if l.credit < float64(n) {
+			return false
+		}
+		l.credit -= float64(n)
+	}
+	l.cur += n
+	if l.held != nil {
+		l.held[tenant] += n
+	}
+	return true
+}
+
+// replenish pays back burst credit for the time elapsed since it was last
+// called. Credit only accrues while the limiter is not bursting, so it must
+// be called before cur or size change. l.mu must be held.
+func (l *limiter) replenish() {
+	if l.burst <= 0 {
+		return
+	}
+	now := time.Now()
+	if l.cur <= l.size {
+		if l.window <= 0 {
+			l.credit = float64(l.burst)
+		} else {
+			l.credit += float64(l.burst) * float64(now.Sub(l.last)) / float64(l.window)
+		}
+		if l.credit > float64(l.burst) {
+			l.credit = float64(l.burst)
+		}
+	}
+	l.last = now
+}
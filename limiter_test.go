@@ -0,0 +1,262 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurst(t *testing.T) {
+	type op struct {
+		release bool
+		n       int64
+		want    bool // for tryAcquire
+	}
+	tests := []struct {
+		name   string
+		size   int64
+		burst  int64
+		window time.Duration
+		ops    []op
+	}{
+		{
+			name: "no burst",
+			size: 1,
+			ops:  []op{{n: 1, want: true}, {n: 1, want: false}},
+		},
+		{
+			name:   "burst above size",
+			size:   1,
+			burst:  2,
+			window: time.Hour,
+			ops: []op{
+				{n: 1, want: true},
+				{n: 2, want: true},
+				{n: 1, want: false},
+			},
+		},
+		{
+			name:   "burst capped by credit",
+			size:   1,
+			burst:  1,
+			window: time.Hour,
+			ops: []op{
+				{n: 2, want: false},
+				{n: 1, want: true},
+				{n: 1, want: true},
+				{release: true, n: 1},
+				{n: 1, want: false},
+			},
+		},
+		{
+			name:  "credit repaid once back within size",
+			size:  1,
+			burst: 1,
+			ops: []op{
+				{n: 1, want: true},
+				{n: 1, want: true},
+				{release: true, n: 1},
+				{n: 1, want: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimiter(tt.size)
+			if tt.burst > 0 {
+				l.setBurst(tt.burst, tt.window)
+			}
+			for i, op := range tt.ops {
+				if op.release {
+					l.release("", op.n)
+					continue
+				}
+				if got := l.tryAcquire("", op.n); got != op.want {
+					t.Fatalf("op %d: tryAcquire(%d) = %v, want %v", i, op.n, got, op.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterGrants(t *testing.T) {
+	type req struct {
+		tenant string
+		n      int64
+	}
+	tests := []struct {
+		name  string
+		size  int64
+		setup func(l *limiter)
+		// held is acquired up front, queue is then queued in order, and
+		// release, if non-zero, is released last. want lists the indices
+		// into queue that have been granted at the end, and rejected those
+		// that failed with ErrQuotaExceeded.
+		held     []req
+		queue    []req
+		release  req
+		want     []int
+		rejected []int
+	}{
+		{
+			name:    "equal weights favor the tenant below its share",
+			size:    2,
+			setup:   func(l *limiter) { l.setWeights(map[string]float64{"a": 1, "b": 1}) },
+			held:    []req{{"a", 1}, {"a", 1}},
+			queue:   []req{{"a", 1}, {"b", 1}},
+			release: req{"a", 1},
+			want:    []int{1},
+		},
+		{
+			name:    "heavier tenant gets a larger share",
+			size:    2,
+			setup:   func(l *limiter) { l.setWeights(map[string]float64{"a": 3, "b": 1}) },
+			held:    []req{{"a", 1}, {"a", 1}},
+			queue:   []req{{"a", 1}, {"b", 1}},
+			release: req{"a", 1},
+			want:    []int{0},
+		},
+		{
+			name:  "tenant at in-flight quota is passed over",
+			size:  2,
+			setup: func(l *limiter) { l.setQuotas(map[string]Quota{"a": {MaxInFlight: 1}}) },
+			held:  []req{{"a", 1}},
+			queue: []req{{"a", 1}, {"b", 1}},
+			want:  []int{1},
+		},
+		{
+			name:     "tenant at queue quota is rejected",
+			size:     1,
+			setup:    func(l *limiter) { l.setQuotas(map[string]Quota{"a": {MaxQueued: 1}}) },
+			held:     []req{{"b", 1}},
+			queue:    []req{{"a", 1}, {"a", 1}, {"b", 1}},
+			release:  req{"b", 1},
+			want:     []int{0},
+			rejected: []int{1},
+		},
+		{
+			name:  "request above in-flight quota is rejected",
+			size:  4,
+			setup: func(l *limiter) { l.setQuotas(map[string]Quota{"a": {MaxInFlight: 1}}) },
+			held:  []req{{"b", 4}},
+			queue: []req{{"a", 2}},
+			// Rejected without being queued.
+			rejected: []int{0},
+		},
+		{
+			name:    "FIFO order",
+			size:    2,
+			held:    []req{{"", 2}},
+			queue:   []req{{"", 1}, {"", 1}, {"", 1}},
+			release: req{"", 2},
+			want:    []int{0, 1},
+		},
+		{
+			name:    "large head blocks smaller waiters",
+			size:    2,
+			held:    []req{{"", 2}},
+			queue:   []req{{"", 2}, {"", 1}},
+			release: req{"", 1},
+		},
+		{
+			name: "strict FIFO does not pass over a tenant at quota",
+			size: 2,
+			setup: func(l *limiter) {
+				l.setQuotas(map[string]Quota{"a": {MaxInFlight: 1}})
+				l.setFIFO()
+			},
+			held:  []req{{"a", 1}},
+			queue: []req{{"a", 1}, {"b", 1}},
+		},
+		{
+			name: "strict FIFO ignores weights",
+			size: 2,
+			setup: func(l *limiter) {
+				l.setWeights(map[string]float64{"a": 1, "b": 1})
+				l.setFIFO()
+			},
+			held:    []req{{"a", 1}, {"a", 1}},
+			queue:   []req{{"a", 1}, {"b", 1}},
+			release: req{"a", 1},
+			want:    []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			l := newLimiter(tt.size)
+			if tt.setup != nil {
+				tt.setup(l)
+			}
+			for _, r := range tt.held {
+				if !l.tryAcquire(r.tenant, r.n) {
+					t.Fatalf("tryAcquire(%q, %d) = false, want true", r.tenant, r.n)
+				}
+			}
+
+			results := make([]chan error, len(tt.queue))
+			for i, r := range tt.queue {
+				results[i] = enqueue(t, ctx, l, r.tenant, r.n)
+			}
+			if tt.release.n != 0 {
+				l.release(tt.release.tenant, tt.release.n)
+			}
+
+			// Grants happen synchronously, so whatever is still queued now
+			// stays queued.
+			if n, _ := l.waiting(); n != len(tt.queue)-len(tt.want)-len(tt.rejected) {
+				t.Errorf("%d waiters still queued, want %d", n, len(tt.queue)-len(tt.want)-len(tt.rejected))
+			}
+			for _, i := range tt.want {
+				if err := result(t, results[i]); err != nil {
+					t.Errorf("waiter %d: acquire() = %v, want nil", i, err)
+				}
+			}
+			for _, i := range tt.rejected {
+				if err := result(t, results[i]); !errors.Is(err, ErrQuotaExceeded) {
+					t.Errorf("waiter %d: acquire() = %v, want ErrQuotaExceeded", i, err)
+				}
+			}
+		})
+	}
+}
+
+// enqueue calls l.acquire on a new goroutine and waits until it has either
+// queued or returned, so that calls are queued in a known order. The result
+// of acquire is sent on the returned channel.
+func enqueue(t *testing.T, ctx context.Context, l *limiter, tenant string, n int64) chan error {
+	t.Helper()
+
+	before, _ := l.waiting()
+	errc := make(chan error, 1)
+	go func() { errc <- l.acquire(ctx, tenant, n) }()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if queued, _ := l.waiting(); queued > before || len(errc) > 0 {
+			return errc
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("acquire(%q, %d) neither queued nor returned", tenant, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// result returns the result sent on errc, failing the test if none arrives
+// in time.
+func result(t *testing.T, errc chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-errc:
+		errc <- err
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("acquire() did not return")
+		return nil
+	}
+}
@@ -5,10 +5,13 @@ package limitgroup
 
 import (
 	"context"
-	"runtime"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/sync/semaphore"
 )
 
 // Group works exactly like a golang.org/x/sync/errgroup.Group, but limits the
@@ -16,50 +19,602 @@ import (
 //
 // A zero Group is invalid. Use WithContext to construct a new Group.
 type Group struct {
+	eg  *errgroup.Group
+	ctx context.Context
+	sem *limiter
+
+	// The arguments the Group was created with, for CloneConfig.
 	limit int64
-	eg    *errgroup.Group
-	ctx   context.Context
-	sem   *semaphore.Weighted
+	opts  []Option
+
+	trackProcs    bool
+	strictFIFO    bool
+	burst         int64
+	burstWindow   time.Duration
+	rampStep      int64
+	rampInterval  time.Duration
+	schedule      func(time.Time) int64
+	scheduleEvery time.Duration
+	recordResults bool
+	maxTaskErrors int
+	weights       map[string]float64
+	quotas        map[string]Quota
+
+	durations       *durations
+	admitPercentile float64
+	saturation      *saturation
+	events          *events
+	journal         *journal
+	errPolicy       ErrorPolicy
+	overflow        func(inFlight, limit int64)
+	taskCtx         func(context.Context, TaskInfo) context.Context
+	maxTasks        int64
+	budget          time.Duration
+	resources       map[string]*limiter
+	accounting      *accounting
+	labels          map[string]string
+
+	mu      sync.Mutex
+	tasks   int
+	results []TaskResult
+	// Errors kept in results, and those suppressed by maxTaskErrors.
+	taskErrors int
+	suppressed int
+	running    map[int]TaskInfo
+	// selected is the error chosen so far by errPolicy.
+	selected TaskResult
+	firstErr error
+	// Functions registered with Defer, until Wait has run them.
+	deferred  []func(error)
+	deferDone bool
+	waitErr   error
+
+	limitMu  sync.Mutex
+	target   int64 // The limit, before any ramp-up.
+	ramp     int64 // The ramp-up limit, or zero once ramp-up is complete.
+	limitSet bool  // Whether SetLimit has been called.
+
+	// Goroutines started by spawn, and a channel closed once none are left.
+	idleMu sync.Mutex
+	active int
+	idle   chan struct{}
+
+	attached        chan error
+	pendingAttached int64 // Calls to Add not yet matched by DoneWith.
+	closed          int32 // Set once Wait has returned.
+	over            int64 // Subtasks running over a soft limit.
+	admitted        int64 // Subtasks admitted, counted only with maxTasks.
+
+	cancel      context.CancelFunc // Cancels the Group's Context.
+	wrapped     bool               // Whether the Group was made by Wrap.
+	budgetOnce  sync.Once          // Starts budgetTimer.
+	budgetTimer *time.Timer
+	budgetSpent int32 // Set once the time budget has run out.
 }
 
 // WithContext returns a new Group and an associated Context derived from ctx.
 //
 // If the given limit is less than or equal to zero, a default of two times
 // the number of CPUs is used.
-func WithContext(ctx context.Context, limit int64) (*Group, context.Context) {
+func WithContext(ctx context.Context, limit int64, opts ...Option) (*Group, context.Context) {
+	// The extra layer lets WithTimeBudget cancel the Group, and Wait stop the
+	// Group's own goroutines.
+	ctx, cancel := context.WithCancel(ctx)
+	eg, ctx := errgroup.WithContext(ctx)
+	lg := newGroup(ctx, eg, limit, opts)
+	lg.cancel = cancel
+	return lg, lg.ctx
+}
+
+// Wrap returns a Group that limits the subtasks it runs on an existing
+// errgroup.Group, e.g. one shared with code that has not yet moved to this
+// package. Functions passed to eg.Go directly are not limited, but Wait on the
+// returned Group waits for them too.
+//
+// ctx should be the Context returned alongside eg by errgroup.WithContext, or
+// the Context the work is done under if eg has none; the Group uses it to stop
+// waiting for slots when it is done. The limit is interpreted as for
+// WithContext.
+func Wrap(ctx context.Context, eg *errgroup.Group, limit int64, opts ...Option) *Group {
+	// As in WithContext, so that Wait stops the Group's own goroutines even
+	// if ctx lives on.
+	ctx, cancel := context.WithCancel(ctx)
+	lg := newGroup(ctx, eg, limit, opts)
+	lg.cancel, lg.wrapped = cancel, true
+	return lg
+}
+
+// Serialized returns a new Group and an associated Context derived from ctx,
+// like WithContext, that runs one subtask at a time, strictly in the order
+// they were submitted: an ordered task queue that stops at the first error
+// and can be canceled through ctx.
+//
+// Subtasks submitted concurrently are ordered by when they called Go. Options
+// that let more than one subtask run at once, such as WithBurst,
+// WithRampUp, WithLimitSchedule, or WithSoftLimit, or a later call to
+// SetLimit, void the guarantee.
+func Serialized(ctx context.Context, opts ...Option) (*Group, context.Context) {
+	return WithContext(ctx, 1, append(opts[:len(opts):len(opts)], WithStrictFIFO())...)
+}
+
+// CloneConfig returns a new Group and an associated Context derived from ctx,
+// like WithContext, created with the same limit and options as lg followed by
+// the given ones, which therefore take precedence. This lets per-request
+// Groups be stamped out from a template Group.
+//
+// Only the configuration is copied: the new Group has its own slots and
+// state, and does not inherit changes made to lg since it was created, such
+// as by SetLimit.
+func (lg *Group) CloneConfig(ctx context.Context, opts ...Option) (*Group, context.Context) {
+	return WithContext(ctx, lg.limit, append(lg.opts[:len(lg.opts):len(lg.opts)], opts...)...)
+}
+
+// newGroup returns a new Group that runs subtasks on eg.
+func newGroup(ctx context.Context, eg *errgroup.Group, limit int64, opts []Option) *Group {
+	var lg Group
+	for _, opt := range opts {
+		opt(&lg)
+	}
+	lg.eg, lg.ctx = eg, ctx
+	lg.limit, lg.opts = limit, opts
+	lg.attached = make(chan error)
+	lg.running = make(map[int]TaskInfo)
+
+	trackProcs := lg.trackProcs && limit <= 0 && lg.schedule == nil
+	if lg.schedule != nil {
+		if scheduled := lg.schedule(time.Now()); scheduled > 0 {
+			limit = scheduled
+		}
+	}
 	if limit <= 0 {
-		limit = int64(runtime.NumCPU() * 2)
+		limit = defaultLimit(lg.trackProcs)
+	}
+	lg.target = limit
+	if lg.ramp >= limit {
+		lg.ramp = 0
+	}
+	lg.sem = newLimiter(lg.effectiveLimit())
+	if lg.saturation != nil {
+		lg.sem.onChange = func() { lg.saturation.update(&lg) }
+	}
+	if lg.strictFIFO {
+		lg.sem.setFIFO()
+	}
+	if lg.burst > 0 {
+		lg.sem.setBurst(lg.burst, lg.burstWindow)
+	}
+	if lg.weights != nil {
+		lg.sem.setWeights(lg.weights)
+	}
+	if lg.quotas != nil {
+		lg.sem.setQuotas(lg.quotas)
+	}
+	if trackProcs {
+		go lg.trackGOMAXPROCS()
+	}
+	if lg.schedule != nil {
+		go lg.followSchedule()
+	}
+	if lg.ramp > 0 {
+		go lg.rampUp()
 	}
-	lg := Group{limit: limit, sem: semaphore.NewWeighted(limit)}
-	lg.eg, lg.ctx = errgroup.WithContext(ctx)
-	return &lg, lg.ctx
+	return &lg
 }
 
 // Go calls the given function in a new goroutine after a semphore is acquired.
 // If there is an error acquiring the semaphore, the error cancels the Group
-// and is returned.
+// and is returned, unless it matches ErrShed, in which case the subtask is
+// dropped without affecting the Group. Calling Go after Wait has returned
+// fails with ErrGroupClosed.
+//
+// Callers blocked in Go are granted slots in the order they called it, unless
+// tenant weights or quotas dictate otherwise; see WithStrictFIFO.
 //
 // The first call to return a non-nil error cancels the group; its error will be
 // returned by Wait.
 func (lg *Group) Go(f func() error) {
-	err := lg.sem.Acquire(lg.ctx, 1)
-	lg.eg.Go(func() error {
-		if err != nil {
-			return err
+	lg.submit(lg.newTask(""), f)
+}
+
+// GoNamed is like Go, but gives the subtask a name that identifies it in
+// TaskResults and the Group's journal.
+func (lg *Group) GoNamed(name string, f func() error) {
+	lg.submit(lg.newNamedTask(name, ""), f)
+}
+
+// GoContext is like Go, but passes the given function the Group's Context,
+// decorated for the subtask by the hook set with WithTaskContext, if any.
+func (lg *Group) GoContext(f func(ctx context.Context) error) {
+	t := lg.newTask("")
+	lg.submit(t, func() error { return f(lg.taskContext(lg.ctx, t)) })
+}
+
+// GoCost is like Go, but the subtask occupies cost slots rather than one,
+// so that the Group's limit caps the total cost of the subtasks in flight,
+// e.g. bytes being processed rather than a number of subtasks. A cost of
+// zero or less counts as one. A cost above the limit is capped at the limit
+// when the subtask is admitted, so that it runs once every slot is free.
+// During ramp-up, the cap is the limit being ramped up to, so such a subtask
+// waits for ramp-up to finish; lowering the limit below its cost while it
+// waits leaves it waiting until the limit is raised again. The global limit
+// set with SetGlobalLimit still counts each subtask once.
+func (lg *Group) GoCost(cost int64, f func() error) {
+	t := lg.newTask("")
+	t.cost = cost
+	lg.submit(t, f)
+}
+
+// GoTenant is like Go, but runs the given function on behalf of tenant. When
+// the Group was created with WithTenantWeights, contended slots are shared
+// between tenants according to their weights.
+//
+// If the submission would exceed the tenant's quota, set with
+// WithTenantQuotas, the function is not run and ErrQuotaExceeded is returned.
+// Since only the offending tenant is affected, the Group is not canceled.
+func (lg *Group) GoTenant(tenant string, f func() error) error {
+	t := lg.newTask(tenant)
+	err := lg.acquire(lg.ctx, &t)
+	switch {
+	case errors.Is(err, ErrQuotaExceeded):
+		lg.shedTask(t, err)
+		return err
+	case err != nil:
+		lg.fail(t, err)
+		return nil
+	}
+	lg.run(t, f)
+	return nil
+}
+
+// TryGo calls the given function in a new goroutine only if a slot is
+// available without waiting, and reports whether it did.
+func (lg *Group) TryGo(f func() error) bool {
+	t := lg.newTask("")
+	if err := lg.admit(lg.ctx); err != nil {
+		lg.shedTask(t, err)
+		return false
+	}
+	if !lg.tryAcquireSlot(&t) {
+		lg.unadmit()
+		lg.shedTask(t, ErrShed)
+		return false
+	}
+	lg.run(t, f)
+	return true
+}
+
+// GoOrElse is like TryGo, but calls fallback on the calling goroutine when no
+// slot is available, e.g. to serve cached data or hand the work off
+// elsewhere. It returns the error from fallback, which does not cancel the
+// Group, or nil if f was started.
+func (lg *Group) GoOrElse(f, fallback func() error) error {
+	if lg.TryGo(f) {
+		return nil
+	}
+	return fallback()
+}
+
+// GoWithin is like Go, but waits at most d for a slot. It reports whether
+// the function was started; if not, the Group is unaffected.
+func (lg *Group) GoWithin(d time.Duration, f func() error) bool {
+	t := lg.newTask("")
+	if err := lg.admit(lg.ctx); err != nil {
+		lg.shedTask(t, err)
+		return false
+	}
+	ctx, cancel := context.WithTimeout(lg.ctx, d)
+	defer cancel()
+
+	if err := lg.acquireSlot(ctx, &t); err != nil {
+		lg.unadmit()
+		if lg.ctx.Err() == nil {
+			err = ErrShed
+		}
+		lg.shedTask(t, err)
+		return false
+	}
+	lg.run(t, f)
+	return true
+}
+
+// submit blocks until a slot is available for t, then runs f in a new
+// goroutine.
+func (lg *Group) submit(t task, f func() error) {
+	if err := lg.acquire(lg.ctx, &t); err != nil {
+		lg.fail(t, err)
+		return
+	}
+	lg.run(t, f)
+}
+
+// fail records that t could not be started because of err, which cancels
+// the Group unless t was merely shed.
+func (lg *Group) fail(t task, err error) {
+	if errors.Is(err, ErrShed) {
+		lg.shedTask(t, err)
+		return
+	}
+	lg.spawn(func() error {
+		lg.finishTask(t, time.Time{}, err)
+		return err
+	})
+}
+
+// reject is like fail, but for use on one of the Group's goroutines: it
+// returns the error the goroutine should return, nil if t was merely shed.
+func (lg *Group) reject(t task, err error) error {
+	if errors.Is(err, ErrShed) {
+		lg.shedTask(t, err)
+		return nil
+	}
+	lg.finishTask(t, time.Time{}, err)
+	return err
+}
+
+// run calls the given function in a new goroutine, releasing the slot held
+// on behalf of t once it returns.
+func (lg *Group) run(t task, f func() error) {
+	lg.spawn(func() error {
+		return lg.call(t, f)
+	})
+}
+
+// call calls the given function on the calling goroutine, releasing the slot
+// held on behalf of t once it returns.
+func (lg *Group) call(t task, f func() error) error {
+	defer lg.release(t)
+
+	start := lg.startTask(t)
+	done := lg.account(t)
+	err := f()
+	done()
+	lg.finishTask(t, start, err)
+	return err
+}
+
+// GoAfter is like Go, but submits the function after d has elapsed instead
+// of straight away, and never blocks the caller. If the Group's context is
+// done before then, the function is dropped without affecting the Group.
+// Wait waits for pending delayed functions too.
+func (lg *Group) GoAfter(d time.Duration, f func() error) {
+	t := lg.newTask("")
+	lg.spawn(func() error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-lg.ctx.Done():
+			lg.shedTask(t, lg.ctx.Err())
+			return nil
+		case <-timer.C:
+		}
+
+		if err := lg.acquire(lg.ctx, &t); err != nil {
+			return lg.reject(t, err)
+		}
+		return lg.call(t, f)
+	})
+}
+
+// GoEvery calls f in a new goroutine immediately and then once every
+// interval, each time under the Group's limit, until the Group's context is
+// done. Like GoContext, each call gets its own decorated Context. The first
+// call to return a non-nil error cancels the Group, exactly like a function
+// passed to Go, and stops the schedule.
+//
+// Wait does not return until the schedule has stopped, so the Group must be
+// canceled, either by an error or through the parent Context, for Wait to
+// return. An interval that is not positive fails like any other error
+// acquiring a slot, without f ever being called.
+func (lg *Group) GoEvery(interval time.Duration, f func(ctx context.Context) error) {
+	if interval <= 0 {
+		lg.fail(lg.newTask(""), fmt.Errorf("limitgroup: non-positive GoEvery interval %v", interval))
+		return
+	}
+	lg.spawn(func() error {
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+
+		for {
+			t := lg.newTask("")
+			if err := lg.acquire(lg.ctx, &t); err != nil {
+				if lg.ctx.Err() != nil {
+					lg.shedTask(t, err)
+					return nil
+				}
+				// A shed call is skipped until the next tick.
+				if err := lg.reject(t, err); err != nil {
+					return err
+				}
+			} else if err := lg.call(t, func() error { return f(lg.taskContext(lg.ctx, t)) }); err != nil {
+				return err
+			}
+
+			select {
+			case <-lg.ctx.Done():
+				return nil
+			case <-tick.C:
+			}
 		}
-		defer lg.sem.Release(1)
+	})
+}
 
-		return f()
+// Do acquires a slot, blocking until one is available or ctx is done, then
+// calls the given function on the calling goroutine and releases the slot
+// once it returns. This lets synchronous work share the Group's concurrency
+// budget with its subtasks.
+//
+// The error from f, or from acquiring the slot, is returned to the caller.
+// Unlike Go, it does not cancel the Group. The deadline of ctx, rather than
+// that of the Group's context, is used for deadline-aware admission.
+func (lg *Group) Do(ctx context.Context, f func() error) error {
+	var t task
+	if err := lg.acquire(ctx, &t); err != nil {
+		return err
+	}
+	defer lg.release(t)
+
+	return f()
+}
+
+// Add attaches a goroutine that was not started by the Group, e.g. one
+// spawned by a third-party callback, so that Wait also waits for it and its
+// error, passed to DoneWith, cancels the Group like that of any subtask.
+// Attached goroutines do not occupy a slot.
+//
+// Every call to Add must be matched by exactly one call to DoneWith.
+func (lg *Group) Add() {
+	atomic.AddInt64(&lg.pendingAttached, 1)
+	lg.spawn(func() error {
+		return <-lg.attached
 	})
 }
 
+// DoneWith reports that a goroutine attached with Add has finished with the
+// given error. Like a negative sync.WaitGroup counter, calling DoneWith more
+// times than Add panics.
+func (lg *Group) DoneWith(err error) {
+	if atomic.AddInt64(&lg.pendingAttached, -1) < 0 {
+		atomic.AddInt64(&lg.pendingAttached, 1)
+		panic("limitgroup: DoneWith called without a matching Add")
+	}
+	if err != nil {
+		lg.selectError(TaskResult{ID: -1, Err: err})
+	}
+	lg.attached <- err
+}
+
 // Wait blocks until all function calls from the Go method have returned,
-// then returns the first non-nil error (if any) from them.
+// then returns the first non-nil error (if any) from them, or the one chosen
+// by the Group's ErrorPolicy. This is always the error Err reports once
+// every subtask has returned.
 func (lg *Group) Wait() error {
-	return lg.eg.Wait()
+	err := lg.eg.Wait()
+	// Subtasks can fail concurrently, so the error errgroup saw first need
+	// not be the one recorded first; prefer the latter, as Err does. Only
+	// functions passed directly to a wrapped errgroup.Group go unrecorded.
+	lg.mu.Lock()
+	switch {
+	case lg.errPolicy != nil && lg.selected.Err != nil:
+		err = lg.selected.Err
+	case lg.errPolicy == nil && lg.firstErr != nil:
+		err = lg.firstErr
+	}
+	lg.mu.Unlock()
+	err = lg.budgetErr(err)
+	lg.stopBudget()
+	if lg.cancel != nil {
+		lg.cancel()
+	}
+	atomic.StoreInt32(&lg.closed, 1)
+	lg.runDeferred(err)
+	if lg.events != nil {
+		lg.events.close()
+	}
+	return err
+}
+
+// Defer registers f to be called once every subtask has returned, before the
+// first call to Wait returns, with the error Wait returns. Deferred functions
+// are called in the reverse order they were registered, like deferred calls,
+// so that cleanup such as closing connections or removing temporary files
+// can be tied to the Group rather than to the code that waits for it.
+//
+// A function deferred after Wait has returned is called straight away.
+func (lg *Group) Defer(f func(cause error)) {
+	lg.mu.Lock()
+	if lg.deferDone {
+		err := lg.waitErr
+		lg.mu.Unlock()
+		f(err)
+		return
+	}
+	lg.deferred = append(lg.deferred, f)
+	lg.mu.Unlock()
+}
+
+// runDeferred calls the functions registered with Defer, the first time it
+// is called.
+func (lg *Group) runDeferred(err error) {
+	lg.mu.Lock()
+	if lg.deferDone {
+		lg.mu.Unlock()
+		return
+	}
+	lg.deferDone, lg.waitErr = true, err
+	deferred := lg.deferred
+	lg.deferred = nil
+	lg.mu.Unlock()
+
+	for i := len(deferred) - 1; i >= 0; i-- {
+		deferred[i](err)
+	}
+}
+
+// WaitAll blocks until all function calls from the Go method have returned,
+// then returns the outcome of each of them in the order they were submitted.
+//
+// Outcomes are only recorded for a Group created with WithTaskResults; for
+// any other Group, WaitAll returns nil.
+func (lg *Group) WaitAll() []TaskResult {
+	lg.Wait()
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	return append([]TaskResult(nil), lg.results...)
+}
+
+// Err returns the error that Wait would return if every subtask still running
+// succeeded, or nil if no subtask has failed yet, without blocking. This lets
+// a producer stop submitting as soon as the Group has failed. Errors from
+// functions passed directly to a wrapped errgroup.Group are not seen.
+func (lg *Group) Err() error {
+	lg.mu.Lock()
+	err := lg.firstErr
+	if lg.errPolicy != nil {
+		err = lg.selected.Err
+	}
+	lg.mu.Unlock()
+	return lg.budgetErr(err)
+}
+
+// Cause returns why the Group's Context is done: the error returned by Err,
+// if any, ErrTimeBudgetExceeded, or otherwise the Context's own error, e.g.
+// because the parent Context was canceled or Wait has returned. It returns
+// nil while the Context is not done.
+func (lg *Group) Cause() error {
+	if lg.ctx.Err() == nil {
+		return nil
+	}
+	if err := lg.Err(); err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&lg.budgetSpent) != 0 {
+		return ErrTimeBudgetExceeded
+	}
+	return lg.ctx.Err()
+}
+
+// InFlight returns the number of slots currently in use, whether by running
+// subtasks, calls to Do, or outstanding Reservations, plus any subtasks
+// running over a soft limit. It can exceed the limit while the Group is
+// bursting or has overflowed a soft limit.
+func (lg *Group) InFlight() int64 {
+	cur, _ := lg.sem.usage()
+	return cur + atomic.LoadInt64(&lg.over)
+}
+
+// Available returns the number of slots that are currently free. Once it
+// reaches zero, calls to Go block unless burst capacity is available.
+func (lg *Group) Available() int64 {
+	cur, size := lg.sem.usage()
+	if cur >= size {
+		return 0
+	}
+	return size - cur
 }
 
-// Limit returns the maximum level of concurrency for the Group.
-func (lg Group) Limit() int64 {
-	return lg.limit
+// Saturated reports whether every slot is in use.
+func (lg *Group) Saturated() bool {
+	return lg.Available() == 0
 }
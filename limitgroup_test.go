@@ -0,0 +1,183 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSerialized(t *testing.T) {
+	lg, _ := Serialized(context.Background())
+
+	var got []int
+	for i := 0; i < 50; i++ {
+		lg.Go(func() error {
+			got = append(got, i)
+			return nil
+		})
+	}
+	if err := lg.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+
+	if len(got) != 50 {
+		t.Fatalf("ran %d subtasks, want 50", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("subtask %d ran at position %d; order = %v", v, i, got)
+		}
+	}
+}
+
+func TestGoCostDuringRampUp(t *testing.T) {
+	lg, _ := WithContext(context.Background(), 8, WithRampUp(1, 7, 10*time.Millisecond))
+
+	var running, peak int64
+	for i := 0; i < 2; i++ {
+		lg.GoCost(8, func() error {
+			n := atomic.AddInt64(&running, 1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+					break
+				}
+			}
+			// Outlast ramp-up, so the other subtask could start alongside.
+			time.Sleep(50 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+			return nil
+		})
+	}
+	if err := lg.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if peak != 1 {
+		t.Errorf("%d subtasks costing the whole limit ran at once, want 1", peak)
+	}
+}
+
+func TestWaitAllMaxTaskErrors(t *testing.T) {
+	// With a limit of one, the subtasks fail in the order they were
+	// submitted. GoValue keeps the failures from canceling the Group.
+	lg, _ := WithContext(context.Background(), 1, WithTaskResults(), WithMaxTaskErrors(2))
+
+	errs := make([]error, 5)
+	for i := range errs {
+		errs[i] = errors.New(strconv.Itoa(i))
+		GoValue(lg, func(context.Context) (int, error) { return 0, errs[i] })
+	}
+	GoValue(lg, func(context.Context) (int, error) { return 0, nil })
+
+	results := lg.WaitAll()
+	if len(results) != 6 {
+		t.Fatalf("WaitAll() returned %d results, want 6", len(results))
+	}
+	for i, res := range results[:5] {
+		want := errs[i]
+		if i >= 2 {
+			want = ErrSuppressed
+		}
+		if res.Err != want {
+			t.Errorf("result %d: Err = %v, want %v", i, res.Err, want)
+		}
+	}
+	if res := results[5]; res.Err != nil {
+		t.Errorf("result 5: Err = %v, want nil", res.Err)
+	}
+	if got := lg.Stats().SuppressedErrors; got != 3 {
+		t.Errorf("SuppressedErrors = %d, want 3", got)
+	}
+}
+
+func TestGoEveryInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		lg, _ := WithContext(context.Background(), 1)
+		called := false
+		lg.GoEvery(interval, func(context.Context) error {
+			called = true
+			return nil
+		})
+		err := lg.Wait()
+		if err == nil || !strings.Contains(err.Error(), "non-positive GoEvery interval") {
+			t.Errorf("GoEvery(%v): Wait() = %v, want an invalid interval error", interval, err)
+		}
+		if called {
+			t.Errorf("GoEvery(%v) called f", interval)
+		}
+	}
+}
+
+func TestGoEvery(t *testing.T) {
+	errStop := errors.New("stop")
+	lg, _ := WithContext(context.Background(), 1)
+	var calls int
+	lg.GoEvery(time.Millisecond, func(context.Context) error {
+		if calls++; calls == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err := lg.Wait(); !errors.Is(err, errStop) {
+		t.Errorf("Wait() = %v, want %v", err, errStop)
+	}
+	if calls != 3 {
+		t.Errorf("f called %d times, want 3", calls)
+	}
+}
+
+func TestWaitMatchesErr(t *testing.T) {
+	for i := 0; i < 5000; i++ {
+		lg, _ := WithContext(context.Background(), 4)
+		start := make(chan struct{})
+		for j := 0; j < 4; j++ {
+			err := errors.New(strconv.Itoa(j))
+			lg.Go(func() error {
+				<-start
+				return err
+			})
+		}
+		close(start)
+
+		err := lg.Wait()
+		if got := lg.Err(); got != err {
+			t.Fatalf("Err() = %v after Wait() = %v, want the same error", got, err)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	type key struct{}
+	labels := map[string]string{"job": "backfill"}
+	lg, _ := WithContext(context.Background(), 1,
+		WithLabels(labels),
+		WithEvents(16),
+		WithTaskContext(func(ctx context.Context, info TaskInfo) context.Context {
+			return context.WithValue(ctx, key{}, info.Labels["job"])
+		}))
+	labels["job"] = "changed"
+
+	var got any
+	lg.GoContext(func(ctx context.Context) error {
+		got = ctx.Value(key{})
+		return nil
+	})
+	if err := lg.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if got != "backfill" {
+		t.Errorf("WithTaskContext saw label job=%v, want backfill", got)
+	}
+	for e := range lg.Events() {
+		if e.Labels["job"] != "backfill" {
+			t.Errorf("%v event has labels %v, want job=backfill", e.Kind, e.Labels)
+		}
+	}
+	if st := lg.Stats(); st.Labels["job"] != "backfill" {
+		t.Errorf("Stats labels = %v, want job=backfill", st.Labels)
+	}
+}
@@ -0,0 +1,287 @@
+package limitgroup
+
+import (
+	"context"
+	"time"
+)
+
+// An Option configures optional behavior of a Group.
+type Option func(*Group)
+
+// WithGOMAXPROCS derives the default limit from runtime.GOMAXPROCS rather than
+// runtime.NumCPU and keeps it in step with GOMAXPROCS for the life of the
+// Group, so that runtime changes (e.g. a container CPU quota being resized)
+// are picked up automatically.
+//
+// It has no effect when a positive limit is passed to WithContext.
+func WithGOMAXPROCS() Option {
+	return func(lg *Group) {
+		lg.trackProcs = true
+	}
+}
+
+// WithBurst permits up to extra subtasks beyond the limit to be started when
+// the Group is saturated, smoothing out short spikes in load.
+//
+// Burst slots are drawn from a credit of extra slots that is only paid back,
+// at a rate of extra per window, while the Group is running at or below its
+// limit. Sustained load therefore settles back to the steady-state limit
+// rather than permanently running extra subtasks.
+func WithBurst(extra int64, window time.Duration) Option {
+	return func(lg *Group) {
+		lg.burst, lg.burstWindow = extra, window
+	}
+}
+
+// WithTaskResults records the outcome of every subtask run by the Group so
+// that it can be reported by WaitAll.
+//
+// The outcomes are kept for the life of the Group, so this is best suited
+// to batch jobs with a bounded number of subtasks.
+func WithTaskResults() Option {
+	return func(lg *Group) {
+		lg.recordResults = true
+	}
+}
+
+// WithMaxTaskErrors caps the number of errors kept by WithTaskResults at n,
+// so that a large batch failing wholesale does not retain every one of them.
+// The first n subtasks to fail keep their errors; later ones are still
+// reported as failed by WaitAll, but with ErrSuppressed in place of their
+// error, and are counted in Stats.SuppressedErrors. A limit less than or
+// equal to zero keeps every error.
+func WithMaxTaskErrors(n int) Option {
+	return func(lg *Group) {
+		lg.maxTaskErrors = n
+	}
+}
+
+// WithDeadlineAdmission rejects subtasks, with ErrDeadlineWouldExceed, when
+// the context they are submitted with has a deadline that leaves less time
+// than the given percentile, in the range [0, 1], of recently observed
+// subtask durations. For Go that is the Group's context; for Do it is the
+// context passed in.
+//
+// Rejected subtasks are shed, so they do not cancel the Group; Do returns
+// the error to its caller. Rejection only starts once enough subtasks have
+// finished to make the estimate meaningful.
+func WithDeadlineAdmission(percentile float64) Option {
+	return func(lg *Group) {
+		if percentile < 0 {
+			percentile = 0
+		} else if percentile > 1 {
+			percentile = 1
+		}
+		lg.durations = new(durations)
+		lg.admitPercentile = percentile
+	}
+}
+
+// WithTenantWeights enables weighted fair queueing between the tenants that
+// subtasks are submitted on behalf of with GoTenant. Whenever callers are
+// blocked waiting for a slot, the next slot goes to the tenant whose share of
+// the slots in use, relative to its weight, is smallest; within a tenant,
+// slots are granted in FIFO order.
+//
+// Tenants missing from weights, including the anonymous tenant used by Go,
+// have a weight of 1. Without contention, weights have no effect.
+func WithTenantWeights(weights map[string]float64) Option {
+	return func(lg *Group) {
+		lg.weights = weights
+	}
+}
+
+// A Quota bounds how much of a Group a single tenant may use, regardless of
+// how much spare capacity the Group has. A zero field means no bound.
+type Quota struct {
+	// MaxInFlight is the maximum number of slots the tenant may hold at once.
+	// Further subtasks wait until one of the tenant's own subtasks finishes.
+	MaxInFlight int64
+	// MaxQueued is the maximum number of the tenant's callers that may be
+	// blocked waiting for a slot. Further submissions are rejected with
+	// ErrQuotaExceeded.
+	MaxQueued int
+}
+
+// WithTenantQuotas enforces hard per-tenant quotas on subtasks submitted
+// with GoTenant. Tenants missing from quotas are bounded only by the Group's
+// limit.
+func WithTenantQuotas(quotas map[string]Quota) Option {
+	return func(lg *Group) {
+		lg.quotas = quotas
+	}
+}
+
+// WithStrictFIFO guarantees that callers blocked waiting for a slot are
+// granted one strictly in the order they started waiting. With a limit of
+// one, as from Serialized, subtasks therefore run strictly in submission
+// order; with a higher limit, subtasks granted slots at around the same time
+// may still start in any order.
+//
+// Callers are already served in FIFO order by default, but WithTenantWeights
+// and WithTenantQuotas allow later callers to overtake earlier ones. With
+// WithStrictFIFO, weights are ignored and a caller held back by its tenant's
+// in-flight quota also holds back everyone behind it.
+func WithStrictFIFO() Option {
+	return func(lg *Group) {
+		lg.strictFIFO = true
+	}
+}
+
+// WithSaturationCallback calls enter when the Group's utilization, the
+// fraction of its limit that is in use, rises to threshold or above, and exit
+// when it falls back below threshold.
+//
+// The callbacks are called synchronously, one at a time, from whichever
+// goroutine caused the change, so they must return quickly and must not
+// acquire or release slots in the Group themselves.
+func WithSaturationCallback(threshold float64, enter, exit func()) Option {
+	return func(lg *Group) {
+		lg.saturation = &saturation{threshold: threshold, enter: enter, exit: exit}
+	}
+}
+
+// WithEvents makes the Group deliver lifecycle events on the channel returned
+// by Events, which holds up to buffer undelivered events. The Group never
+// blocks on the channel; events that do not fit in the buffer are dropped.
+func WithEvents(buffer int) Option {
+	return func(lg *Group) {
+		lg.events = newEvents(buffer)
+	}
+}
+
+// WithJournal keeps an in-memory record of the last size subtasks to
+// complete, retrievable with Journal, so that a failed batch can be examined
+// afterwards without having had logging enabled.
+func WithJournal(size int) Option {
+	return func(lg *Group) {
+		if size > 0 {
+			lg.journal = newJournal(size)
+		}
+	}
+}
+
+// WithRampUp makes a new Group start with a limit of start, and raise it by
+// step every interval until it reaches the Group's limit, so that cold
+// downstream services are not hit with the full concurrency at once. It has
+// no effect if interval is not positive.
+func WithRampUp(start, step int64, interval time.Duration) Option {
+	return func(lg *Group) {
+		if interval <= 0 {
+			return
+		}
+		if start <= 0 {
+			start = 1
+		}
+		if step <= 0 {
+			step = 1
+		}
+		lg.ramp, lg.rampStep, lg.rampInterval = start, step, interval
+	}
+}
+
+// WithLimitSchedule sets the Group's limit from schedule, which is called
+// with the current time when the Group is created and then every interval,
+// so that load can be shaped by time of day (e.g. 64 during business hours
+// and 256 overnight). Results less than or equal to zero leave the limit
+// unchanged.
+//
+// The schedule replaces the limit passed to WithContext and takes precedence
+// over WithGOMAXPROCS. It stops being followed once SetLimit is called. It
+// has no effect if interval is not positive.
+func WithLimitSchedule(schedule func(time.Time) int64, interval time.Duration) Option {
+	return func(lg *Group) {
+		if interval > 0 {
+			lg.schedule, lg.scheduleEvery = schedule, interval
+		}
+	}
+}
+
+// WithErrorPolicy sets the policy that chooses which error Wait returns when
+// more than one subtask fails. The Group is still canceled by the first
+// failure.
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(lg *Group) {
+		lg.errPolicy = policy
+	}
+}
+
+// WithSoftLimit makes the Group's limit advisory: subtasks submitted while
+// every slot is in use run straight away instead of waiting, and overflow is
+// called with the resulting number of subtasks in flight and the limit. This
+// shows what a proposed limit would hold back before it is enforced.
+//
+// overflow is called synchronously from the submitting goroutine, so it must
+// return quickly. Reservations and the global limit are still enforced.
+func WithSoftLimit(overflow func(inFlight, limit int64)) Option {
+	return func(lg *Group) {
+		lg.overflow = overflow
+	}
+}
+
+// WithTaskContext sets a hook that decorates the Context passed to each
+// subtask started by GoContext or GoEvery, e.g. with the subtask's ID or
+// trace attributes. It is called on the subtask's goroutine just before the
+// subtask runs, with the Group's Context and a description of the subtask.
+func WithTaskContext(decorate func(ctx context.Context, info TaskInfo) context.Context) Option {
+	return func(lg *Group) {
+		lg.taskCtx = decorate
+	}
+}
+
+// WithMaxTasks limits the total number of subtasks the Group accepts over its
+// lifetime to n, guarding against runaway producers. Submissions beyond that
+// fail with ErrMaxTasks, which, as for any error acquiring a slot, cancels
+// the Group when returned from Go; TryGo and the like instead report that the
+// subtask was not started. Only subtasks that are given a slot count
+// towards the total, including those run on a Reservation and calls to Do.
+func WithMaxTasks(n int64) Option {
+	return func(lg *Group) {
+		lg.maxTasks = n
+	}
+}
+
+// WithTimeBudget cancels the Group d after its first subtask starts, rather
+// than after it is created, bounding the running time of a batch of work.
+// Once the budget has run out, Wait returns ErrTimeBudgetExceeded in place
+// of the context errors that subtasks return as a result. It has no effect
+// on a Group created by Wrap.
+func WithTimeBudget(d time.Duration) Option {
+	return func(lg *Group) {
+		lg.budget = d
+	}
+}
+
+// WithResourceAccounting records the heap bytes allocated and the user CPU
+// time spent while each subtask runs, aggregated by subtask name in
+// Stats.Costs, so that capacity can be attributed to kinds of work.
+//
+// The figures are process-wide usage sampled with runtime/metrics as
+// subtasks start and finish. Usage between two samples is divided evenly
+// among the subtasks running at the time, so the totals across names add up
+// to at most what the process used, but they are only a guide when subtasks
+// overlap, and include the work of anything else running then. CPU time in
+// particular is estimated by the runtime and may only be updated at garbage
+// collections.
+func WithResourceAccounting() Option {
+	return func(lg *Group) {
+		lg.accounting = &accounting{
+			costs:   make(map[string]TaskCost),
+			running: make(map[string]int),
+		}
+	}
+}
+
+// WithLabels attaches labels, e.g. job=backfill or tenant=acme, to the
+// telemetry the Group produces: every Event, its Stats, the TaskInfo of each
+// subtask, which the WithTaskContext hook can copy onto trace spans, and its
+// entry in the groupdebug handler. This lets a service running many Groups
+// tell their telemetry apart.
+func WithLabels(labels map[string]string) Option {
+	return func(lg *Group) {
+		lg.labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			lg.labels[k] = v
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package limitgroup
+
+import "sync"
+
+// Ordered runs subtasks on a Group and streams their results on a channel in
+// the order the subtasks were submitted. A result is sent as soon as it and
+// the results of all earlier subtasks are available; results that complete
+// out of order are buffered until then.
+//
+// Subtasks that fail, or that could not be started, produce no result. The
+// error is handled by the Group as usual.
+type Ordered[R any] struct {
+	lg  *Group
+	out chan R
+
+	mu        sync.Mutex
+	cond      sync.Cond
+	submitted int
+	next      int
+	closed    bool
+	pending   map[int]outcome[R]
+}
+
+type outcome[R any] struct {
+	value R
+	ok    bool
+}
+
+// NewOrdered returns an Ordered that runs its subtasks on the given Group.
+//
+// The channel returned by Results must be drained; subtasks themselves never
+// block on it, but undelivered results are buffered in memory.
+func NewOrdered[R any](lg *Group) *Ordered[R] {
+	o := &Ordered[R]{
+		lg:      lg,
+		out:     make(chan R),
+		pending: make(map[int]outcome[R]),
+	}
+	o.cond.L = &o.mu
+	go o.emit()
+	return o
+}
+
+// Go calls the given function in a new goroutine once a slot is available in
+// the Group, exactly like the Go method of the Group, and queues its result
+// for delivery in submission order.
+func (o *Ordered[R]) Go(f func() (R, error)) {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		panic("limitgroup: Go called on closed Ordered")
+	}
+	i := o.submitted
+	o.submitted++
+	o.mu.Unlock()
+
+	t := o.lg.newTask("")
+	if err := o.lg.acquire(o.lg.ctx, &t); err != nil {
+		o.deliver(i, outcome[R]{})
+		o.lg.fail(t, err)
+		return
+	}
+	o.lg.run(t, func() error {
+		v, err := f()
+		o.deliver(i, outcome[R]{value: v, ok: err == nil})
+		return err
+	})
+}
+
+// Close indicates that no more subtasks will be submitted. The channel
+// returned by Results is closed once every submitted subtask has finished and
+// its result, if any, has been delivered.
+func (o *Ordered[R]) Close() {
+	o.mu.Lock()
+	o.closed = true
+	o.cond.Signal()
+	o.mu.Unlock()
+}
+
+// Results returns the channel on which results are delivered.
+func (o *Ordered[R]) Results() <-chan R {
+	return o.out
+}
+
+// deliver queues the outcome of the i'th subtask for emit.
+func (o *Ordered[R]) deliver(i int, res outcome[R]) {
+	o.mu.Lock()
+	o.pending[i] = res
+	o.cond.Signal()
+	o.mu.Unlock()
+}
+
+// emit sends results on the output channel as the contiguous prefix of
+// finished subtasks grows.
+func (o *Ordered[R]) emit() {
+	defer close(o.out)
+	for {
+		o.mu.Lock()
+		res, ok := o.pending[o.next]
+		for !ok && !(o.closed && o.next == o.submitted) {
+			o.cond.Wait()
+			res, ok = o.pending[o.next]
+		}
+		if !ok {
+			o.mu.Unlock()
+			return
+		}
+		delete(o.pending, o.next)
+		o.next++
+		o.mu.Unlock()
+
+		if res.ok {
+			o.out <- res.value
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOrdered(t *testing.T) {
+	lg, _ := WithContext(context.Background(), 4)
+	o := NewOrdered[int](lg)
+
+	done := make(chan []int)
+	go func() {
+		var got []int
+		for v := range o.Results() {
+			got = append(got, v)
+		}
+		done <- got
+	}()
+
+	for i := 0; i < 20; i++ {
+		o.Go(func() (int, error) {
+			// Later subtasks finish first.
+			time.Sleep(time.Duration(20-i) * 100 * time.Microsecond)
+			return i, nil
+		})
+	}
+	o.Close()
+
+	got := <-done
+	if err := lg.Wait(); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+	if len(got) != 20 {
+		t.Fatalf("got %d results, want 20: %v", len(got), got)
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("results out of order: %v", got)
+		}
+	}
+}
+
+func TestOrderedFailure(t *testing.T) {
+	errBad := errors.New("bad")
+	lg, _ := WithContext(context.Background(), 1)
+	o := NewOrdered[int](lg)
+
+	done := make(chan []int)
+	go func() {
+		var got []int
+		for v := range o.Results() {
+			got = append(got, v)
+		}
+		done <- got
+	}()
+
+	o.Go(func() (int, error) { return 0, nil })
+	o.Go(func() (int, error) { return 0, errBad })
+	o.Close()
+
+	got := <-done
+	if err := lg.Wait(); !errors.Is(err, errBad) {
+		t.Errorf("Wait() = %v, want %v", err, errBad)
+	}
+	if len(got) != 1 || got[0] != 0 {
+		t.Errorf("results = %v, want [0]", got)
+	}
+}
+
+func TestOrderedGoAfterClose(t *testing.T) {
+	lg, _ := WithContext(context.Background(), 1)
+	o := NewOrdered[int](lg)
+	o.Close()
+	for range o.Results() {
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Go after Close did not panic")
+		}
+	}()
+	o.Go(func() (int, error) { return 0, nil })
+}
@@ -0,0 +1,44 @@
+package limitgroup
+
+import "context"
+
+// Partition splits items into the given number of contiguous shards, keeping
+// their order, and calls fn once per shard with the shard's index and items,
+// concurrently with the other shards. Every item is therefore seen by exactly
+// one call, in order, so that per-shard state and affinity can be kept.
+//
+// Each call is given a Group of its own, with a limit of limitPerShard, on
+// which it can run subtasks for its shard. The shard is finished once fn and
+// every subtask it submitted have returned. As with WithContext, a
+// limitPerShard less than or equal to zero selects the default limit.
+//
+// The first call to fn, or subtask, to return a non-nil error cancels the
+// context passed to every other call, in every shard; its error is returned.
+func Partition[T any](ctx context.Context, items []T, shards int, limitPerShard int64, fn func(ctx context.Context, lg *Group, shard int, items []T) error) error {
+	if shards <= 0 {
+		shards = 1
+	}
+
+	lg, ctx := WithContext(ctx, int64(shards))
+	for i := 0; i < shards; i++ {
+		shard, part := i, split(items, shards, i)
+		if len(part) == 0 {
+			continue
+		}
+		lg.Go(func() error {
+			sub, ctx := WithContext(ctx, limitPerShard)
+			// fn does not hold one of the shard's slots, but its error still
+			// cancels the shard.
+			sub.Add()
+			sub.DoneWith(fn(ctx, sub, shard, part))
+			return sub.Wait()
+		})
+	}
+	return lg.Wait()
+}
+
+// split returns the i'th of n contiguous, near-equal parts of items.
+func split[T any](items []T, n, i int) []T {
+	lo, hi := len(items)*i/n, len(items)*(i+1)/n
+	return items[lo:hi:hi]
+}
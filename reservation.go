@@ -0,0 +1,104 @@
+package limitgroup
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// A Reservation holds slots acquired from a Group ahead of time, so that
+// capacity can be secured before expensive setup and handed to later calls
+// to Go without blocking.
+//
+// A Reservation is safe for concurrent use. Slots that are not consumed by Go
+// must be returned with Release.
+type Reservation struct {
+	lg     *Group
+	global bool
+
+	mu sync.Mutex
+	n  int64
+}
+
+// Reserve blocks until n slots are available in the Group, or ctx is done,
+// and returns a Reservation holding them. On failure, ctx.Err(), or
+// ErrGroupClosed if the Group's Wait method has returned, is returned and the
+// Group is unchanged.
+//
+// As with GoCost, n is capped at the Group's limit, so that reserving more
+// slots than the Group has waits for all of them to be free rather than
+// forever. If n is zero or less, the Reservation holds no slots.
+func (lg *Group) Reserve(ctx context.Context, n int64) (*Reservation, error) {
+	if atomic.LoadInt32(&lg.closed) != 0 {
+		return nil, ErrGroupClosed
+	}
+	if n <= 0 {
+		return &Reservation{lg: lg}, nil
+	}
+	n = lg.clampWeight(n)
+	if err := lg.sem.acquire(ctx, "", n); err != nil {
+		return nil, err
+	}
+	global, err := acquireGlobal(ctx, n)
+	if err != nil {
+		lg.sem.release("", n)
+		return nil, err
+	}
+	return &Reservation{lg: lg, global: global, n: n}, nil
+}
+
+// Go calls the given function in a new goroutine using one of the reserved
+// slots. If no reserved slots remain, Go behaves exactly like the Go method of
+// the Group the reservation was made from. A reserved slot is still subject
+// to admission, e.g. by WithMaxTasks; if the function is not admitted, the
+// slot is returned to the Group and the error handled as by Go. After the
+// Group's Wait method has returned, the function is not run and is recorded
+// as failing with ErrGroupClosed.
+func (r *Reservation) Go(f func() error) {
+	r.mu.Lock()
+	reserved := r.n > 0
+	if reserved {
+		r.n--
+	}
+	r.mu.Unlock()
+
+	if !reserved {
+		r.lg.Go(f)
+		return
+	}
+	t := r.lg.newTask("")
+	t.global = r.global
+	if err := r.lg.admit(r.lg.ctx); err != nil {
+		r.lg.release(t)
+		if err == ErrGroupClosed {
+			r.lg.shedTask(t, err)
+		} else {
+			r.lg.fail(t, err)
+		}
+		return
+	}
+	r.lg.run(t, f)
+}
+
+// Remaining returns the number of reserved slots that have not yet been used.
+func (r *Reservation) Remaining() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+// Release returns any unused slots to the Group. It is safe to call Release
+// more than once.
+func (r *Reservation) Release() {
+	r.mu.Lock()
+	n := r.n
+	r.n = 0
+	r.mu.Unlock()
+
+	if n > 0 {
+		if r.global {
+			global.release("", n)
+		}
+		r.lg.sem.release("", n)
+	}
+}
@@ -0,0 +1,51 @@
+package limitgroup
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name          string
+		n             int64
+		wantRemaining int64
+	}{
+		{name: "within limit", n: 1, wantRemaining: 1},
+		{name: "whole limit", n: 2, wantRemaining: 2},
+		{name: "above limit", n: 5, wantRemaining: 2},
+		{name: "zero", n: 0, wantRemaining: 0},
+		{name: "negative", n: -3, wantRemaining: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lg, _ := WithContext(context.Background(), 2)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			r, err := lg.Reserve(ctx, tt.n)
+			if err != nil {
+				t.Fatalf("Reserve(%d) = %v, want nil", tt.n, err)
+			}
+			if got := r.Remaining(); got != tt.wantRemaining {
+				t.Errorf("Remaining() = %d, want %d", got, tt.wantRemaining)
+			}
+			if got := lg.InFlight(); got != tt.wantRemaining {
+				t.Errorf("InFlight() = %d, want %d", got, tt.wantRemaining)
+			}
+
+			r.Go(func() error { return nil })
+			r.Release()
+			if err := lg.Wait(); err != nil {
+				t.Fatalf("Wait() = %v, want nil", err)
+			}
+			if got := lg.InFlight(); got != 0 {
+				t.Errorf("InFlight() after Wait = %d, want 0", got)
+			}
+			if got := lg.Available(); got != 2 {
+				t.Errorf("Available() after Wait = %d, want 2", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package limitgroup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// need is an amount of a named resource required by a subtask.
+type need struct {
+	name   string
+	amount int64
+}
+
+// WithResources defines named resources with the given capacities, such as
+// connections or bytes of memory, that subtasks submitted with GoResources
+// draw on in addition to a slot of the Group's limit.
+func WithResources(capacities map[string]int64) Option {
+	return func(lg *Group) {
+		lg.resources = make(map[string]*limiter, len(capacities))
+		for name, capacity := range capacities {
+			lg.resources[name] = newLimiter(capacity)
+		}
+	}
+}
+
+// GoResources is like Go, but the subtask also requires the given amounts of
+// the named resources defined with WithResources, e.g. one "conn" and 64 MiB
+// of "memory". The function only starts once all of them, and a slot, have
+// been acquired. Resources are always acquired in order of name, and before
+// the slot, so that subtasks with overlapping requirements cannot deadlock.
+//
+// Requiring a resource that was not defined, or more of one than its
+// capacity, fails like any other error acquiring a slot.
+func (lg *Group) GoResources(needs map[string]int64, f func() error) {
+	t := lg.newTask("")
+	t.needs = make([]need, 0, len(needs))
+	for name, amount := range needs {
+		if amount > 0 {
+			t.needs = append(t.needs, need{name, amount})
+		}
+	}
+	sort.Slice(t.needs, func(i, j int) bool { return t.needs[i].name < t.needs[j].name })
+	for _, n := range t.needs {
+		r := lg.resources[n.name]
+		if r == nil {
+			lg.fail(t, fmt.Errorf("limitgroup: unknown resource %q", n.name))
+			return
+		}
+		if capacity := r.limit(); n.amount > capacity {
+			lg.fail(t, fmt.Errorf("limitgroup: %d of resource %q exceeds its capacity of %d", n.amount, n.name, capacity))
+			return
+		}
+	}
+	lg.submit(t, f)
+}
+
+// acquireResources acquires each of needs in turn, blocking until all are
+// available or ctx is done. On failure, it leaves none of them held.
+func (lg *Group) acquireResources(ctx context.Context, needs []need) error {
+	for i, n := range needs {
+		if err := lg.resources[n.name].acquire(ctx, "", n.amount); err != nil {
+			lg.releaseResources(needs[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseResources releases each of needs.
+func (lg *Group) releaseResources(needs []need) {
+	for _, n := range needs {
+		lg.resources[n.name].release("", n.amount)
+	}
+}
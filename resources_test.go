@@ -0,0 +1,58 @@
+package limitgroup
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGoResources(t *testing.T) {
+	tests := []struct {
+		name    string
+		needs   []map[string]int64
+		wantRan int64
+		wantErr string
+	}{
+		{
+			name:    "within capacity",
+			needs:   []map[string]int64{{"conn": 1}, {"conn": 2, "mem": 10}},
+			wantRan: 2,
+		},
+		{
+			name:    "unknown resource",
+			needs:   []map[string]int64{{"disk": 1}},
+			wantErr: `unknown resource "disk"`,
+		},
+		{
+			name:    "above capacity",
+			needs:   []map[string]int64{{"conn": 1}, {"conn": 3}},
+			wantRan: 1,
+			wantErr: `3 of resource "conn" exceeds its capacity of 2`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lg, _ := WithContext(context.Background(), 4,
+				WithResources(map[string]int64{"conn": 2, "mem": 10}))
+
+			var ran int64
+			for _, needs := range tt.needs {
+				lg.GoResources(needs, func() error {
+					atomic.AddInt64(&ran, 1)
+					return nil
+				})
+			}
+			err := lg.Wait()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Wait() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Wait() = %v, want error containing %q", err, tt.wantErr)
+			}
+			if ran != tt.wantRan {
+				t.Errorf("%d subtasks ran, want %d", ran, tt.wantRan)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package limitgroup
+
+import "sync"
+
+// saturation tracks whether a Group's utilization is above a threshold and
+// calls the registered callbacks when that changes.
+type saturation struct {
+	threshold   float64
+	enter, exit func()
+
+	mu    sync.Mutex
+	above bool
+}
+
+// update re-evaluates the utilization of the Group, calling enter or exit if
+// it has crossed the threshold. Calls are serialized so that enter and exit
+// always alternate.
+func (s *saturation) update(lg *Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, size := lg.sem.usage()
+	above := cur > 0 && (size <= 0 || float64(cur)/float64(size) >= s.threshold)
+	if above == s.above {
+		return
+	}
+	s.above = above
+	if above {
+		if s.enter != nil {
+			s.enter()
+		}
+	} else if s.exit != nil {
+		s.exit()
+	}
+}
@@ -0,0 +1,34 @@
+package limitgroup
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// GoSeq submits each of the functions yielded by seq as if by GoContext,
+// pulling the next one only once the previous one has been given a slot, so
+// that producers can be written as lazy generators. It returns once seq is
+// exhausted, or stops pulling from seq as soon as the Group's Context is
+// done.
+func (lg *Group) GoSeq(seq iter.Seq[func(ctx context.Context) error]) {
+	for f := range seq {
+		if lg.ctx.Err() != nil {
+			return
+		}
+		lg.GoContext(f)
+	}
+}
+
+// GoSeq2 is like GoSeq, but for a sequence of keys and functions. Each
+// function's subtask is named after its key, formatted as by fmt.Sprint, as
+// with GoNamed.
+func GoSeq2[K any](lg *Group, seq iter.Seq2[K, func(ctx context.Context) error]) {
+	for k, f := range seq {
+		if lg.ctx.Err() != nil {
+			return
+		}
+		t := lg.newNamedTask(fmt.Sprint(k), "")
+		lg.submit(t, func() error { return f(lg.taskContext(lg.ctx, t)) })
+	}
+}
@@ -0,0 +1,48 @@
+package limitgroup
+
+import "time"
+
+// Stats is a snapshot of a Group's usage, as returned by Group.Stats.
+type Stats struct {
+	// Limit is the current limit.
+	Limit int64
+	// InFlight is the number of slots in use, as returned by InFlight.
+	InFlight int64
+	// Waiting is the number of callers currently blocked waiting for a slot,
+	// e.g. in Go, Do, or Reserve. Callers queuing here are the first sign
+	// that a limit is too low.
+	Waiting int
+	// WaitTime is the total time callers have spent blocked waiting for a
+	// slot, including those still waiting.
+	WaitTime time.Duration
+	// Costs holds the resources used by subtasks, keyed by subtask name,
+	// with unnamed subtasks under "". It is nil unless the Group was created
+	// with WithResourceAccounting.
+	Costs map[string]TaskCost
+	// SuppressedErrors is the number of subtask errors not kept by
+	// WithTaskResults because of the cap set with WithMaxTaskErrors.
+	SuppressedErrors int
+	// Labels are the Group's labels, set with WithLabels. The map is shared
+	// and must not be modified.
+	Labels map[string]string
+}
+
+// Stats returns a snapshot of the Group's usage. Time spent waiting for the
+// global limit set with SetGlobalLimit is not included.
+func (lg *Group) Stats() Stats {
+	waiting, waited := lg.sem.waiting()
+	st := Stats{
+		Limit:    lg.Limit(),
+		InFlight: lg.InFlight(),
+		Waiting:  waiting,
+		WaitTime: waited,
+		Labels:   lg.labels,
+	}
+	if lg.accounting != nil {
+		st.Costs = lg.accounting.snapshot()
+	}
+	lg.mu.Lock()
+	st.SuppressedErrors = lg.suppressed
+	lg.mu.Unlock()
+	return st
+}
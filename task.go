@@ -0,0 +1,279 @@
+package limitgroup
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// TaskResult describes the outcome of a single subtask run by a Group.
+type TaskResult struct {
+	// ID identifies the subtask by the order in which it was submitted to the
+	// Group, starting at zero.
+	ID int
+	// Name is the name the subtask was submitted with by GoNamed, if any.
+	Name string
+	// Err is the error returned by the subtask, or the error that prevented
+	// it from starting.
+	Err error
+	// Start is when the subtask started running. It is the zero Time if the
+	// subtask never started.
+	Start time.Time
+	// Duration is how long the subtask ran for.
+	Duration time.Duration
+}
+
+// TaskInfo describes a subtask that has been started by a Group.
+type TaskInfo struct {
+	// ID and Name identify the subtask, as in TaskResult.
+	ID   int
+	Name string
+	// Tenant is the tenant the subtask was submitted on behalf of, if any.
+	Tenant string
+	// Start is when the subtask started running.
+	Start time.Time
+	// Labels are the labels of the Group running the subtask, set with
+	// WithLabels, e.g. for tagging trace spans from WithTaskContext. The map
+	// is shared and must not be modified.
+	Labels map[string]string
+}
+
+// label returns the name of the subtask, falling back to its ID.
+func (ti TaskInfo) label() string {
+	if ti.Name != "" {
+		return ti.Name
+	}
+	return "#" + strconv.Itoa(ti.ID)
+}
+
+// task holds the bookkeeping for a single subtask.
+type task struct {
+	id     int
+	name   string
+	tenant string
+	global bool // Whether a slot is held from the global limit.
+	over   bool // Whether t is running over a soft limit, without a slot.
+	cost   int64
+	needs  []need // Named resources held, in acquisition order.
+}
+
+// weight returns the number of slots t occupies in the Group: its cost, or
+// one if it has none.
+func (t task) weight() int64 {
+	if t.cost > 0 {
+		return t.cost
+	}
+	return 1
+}
+
+// newTask assigns an ID to a newly submitted subtask run on behalf of
+// tenant.
+func (lg *Group) newTask(tenant string) task {
+	return lg.newNamedTask("", tenant)
+}
+
+// newNamedTask is like newTask, but also names the subtask.
+func (lg *Group) newNamedTask(name, tenant string) task {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	t := task{id: lg.tasks, name: name, tenant: tenant}
+	lg.tasks++
+	if lg.recordResults {
+		lg.results = append(lg.results, TaskResult{ID: t.id, Name: name})
+	}
+	lg.emit(Event{Kind: EventSubmitted, TaskID: t.id, TaskName: name})
+	return t
+}
+
+// acquire admits t, submitted with the given context, and blocks until a
+// slot is available for it.
+func (lg *Group) acquire(ctx context.Context, t *task) error {
+	if err := lg.admit(ctx); err != nil {
+		return err
+	}
+	if err := lg.acquireSlot(ctx, t); err != nil {
+		lg.unadmit()
+		return err
+	}
+	return nil
+}
+
+// acquireSlot blocks until a slot is available for t in both the Group and
+// the global limit, as well as any named resources it needs, or ctx is done.
+func (lg *Group) acquireSlot(ctx context.Context, t *task) error {
+	lg.clampCost(t)
+	if err := lg.acquireResources(ctx, t.needs); err != nil {
+		return err
+	}
+	if lg.overflow != nil {
+		lg.overflowSlot(t)
+	} else if err := lg.sem.acquire(ctx, t.tenant, t.weight()); err != nil {
+		lg.releaseResources(t.needs)
+		return err
+	}
+	global, err := acquireGlobal(ctx, 1)
+	if err != nil {
+		lg.releaseSlot(*t)
+		lg.releaseResources(t.needs)
+		return err
+	}
+	t.global = global
+	return nil
+}
+
+// tryAcquireSlot acquires a slot for t in both the Group and the global
+// limit without blocking, and reports whether it succeeded.
+func (lg *Group) tryAcquireSlot(t *task) bool {
+	lg.clampCost(t)
+	if lg.overflow != nil {
+		lg.overflowSlot(t)
+	} else if !lg.sem.tryAcquire(t.tenant, t.weight()) {
+		return false
+	}
+	global, ok := tryAcquireGlobal(1)
+	if !ok {
+		lg.releaseSlot(*t)
+		return false
+	}
+	t.global = global
+	return true
+}
+
+// release returns the slots held on behalf of t.
+func (lg *Group) release(t task) {
+	if t.global {
+		global.release("", 1)
+	}
+	lg.releaseSlot(t)
+	lg.releaseResources(t.needs)
+}
+
+// clampCost caps the cost of t at the Group's target limit, so that a
+// subtask costing more than the whole limit runs on its own instead of
+// blocking every caller queued behind it forever. The ramp-up limit is
+// deliberately ignored, as it only holds back the full cost for a while.
+func (lg *Group) clampCost(t *task) {
+	t.cost = lg.clampWeight(t.cost)
+}
+
+// clampWeight returns n capped at the Group's target limit, if that is
+// positive.
+func (lg *Group) clampWeight(n int64) int64 {
+	lg.limitMu.Lock()
+	limit := lg.target
+	lg.limitMu.Unlock()
+	if limit > 0 && n > limit {
+		return limit
+	}
+	return n
+}
+
+// overflowSlot acquires a slot for t in a Group with a soft limit, or, if
+// none is free, lets t run over the limit and reports the overflow.
+func (lg *Group) overflowSlot(t *task) {
+	if lg.sem.tryAcquire(t.tenant, t.weight()) {
+		return
+	}
+	t.over = true
+	over := atomic.AddInt64(&lg.over, t.weight())
+	cur, size := lg.sem.usage()
+	lg.overflow(cur+over, size)
+}
+
+// releaseSlot returns the Group slot held on behalf of t, if any.
+func (lg *Group) releaseSlot(t task) {
+	if t.over {
+		atomic.AddInt64(&lg.over, -t.weight())
+		return
+	}
+	lg.sem.release(t.tenant, t.weight())
+}
+
+// startTask records that t has started running.
+func (lg *Group) startTask(t task) time.Time {
+	lg.startBudget()
+	lg.emit(Event{Kind: EventStarted, TaskID: t.id, TaskName: t.name})
+	start := time.Now()
+
+	lg.mu.Lock()
+	lg.running[t.id] = TaskInfo{ID: t.id, Name: t.name, Tenant: t.tenant, Start: start, Labels: lg.labels}
+	lg.mu.Unlock()
+	return start
+}
+
+// taskContext returns the Context to pass to t, which must be running: ctx,
+// decorated by the WithTaskContext hook.
+func (lg *Group) taskContext(ctx context.Context, t task) context.Context {
+	if lg.taskCtx == nil {
+		return ctx
+	}
+	lg.mu.Lock()
+	info := lg.running[t.id]
+	lg.mu.Unlock()
+	return lg.taskCtx(ctx, info)
+}
+
+// finishTask records the outcome of t, which either returned err after
+// starting at start or, if start is zero, could not be started because of
+// err.
+func (lg *Group) finishTask(t task, start time.Time, err error) {
+	res := lg.settleTask(t, start, err)
+	if err != nil {
+		lg.selectError(res)
+	}
+}
+
+// settleTask is like finishTask, but leaves err out of the Group's error, for
+// subtasks whose outcome is delivered some other way.
+func (lg *Group) settleTask(t task, start time.Time, err error) TaskResult {
+	kind := EventFinished
+	if err != nil {
+		kind = EventFailed
+	}
+	lg.emit(Event{Kind: kind, TaskID: t.id, TaskName: t.name, Err: err})
+	if !start.IsZero() {
+		lg.mu.Lock()
+		delete(lg.running, t.id)
+		lg.mu.Unlock()
+	}
+	return lg.recordTask(t, start, err)
+}
+
+// shedTask records that t was turned away because of err without affecting
+// the Group.
+func (lg *Group) shedTask(t task, err error) {
+	lg.emit(Event{Kind: EventShed, TaskID: t.id, TaskName: t.name, Err: err})
+	lg.recordTask(t, time.Time{}, err)
+}
+
+// recordTask updates the statistics and result kept for t, and returns the
+// result.
+func (lg *Group) recordTask(t task, start time.Time, err error) TaskResult {
+	res := TaskResult{ID: t.id, Name: t.name, Err: err, Start: start}
+	if !start.IsZero() {
+		res.Duration = time.Since(start)
+		if lg.durations != nil {
+			lg.durations.observe(res.Duration)
+		}
+	}
+	if lg.journal != nil {
+		lg.journal.add(res)
+	}
+	if lg.recordResults {
+		kept := res
+		lg.mu.Lock()
+		if err != nil && lg.maxTaskErrors > 0 {
+			if lg.taskErrors < lg.maxTaskErrors {
+				lg.taskErrors++
+			} else {
+				kept.Err = ErrSuppressed
+				lg.suppressed++
+			}
+		}
+		lg.results[t.id] = kept
+		lg.mu.Unlock()
+	}
+	return res
+}
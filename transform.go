@@ -0,0 +1,77 @@
+package limitgroup
+
+import "context"
+
+// Transform starts a pipeline stage that calls fn for every value received
+// from in, with at most limit calls in flight at once, and sends the results
+// on the returned value channel in the order they complete. Use Ordered for
+// a stage that preserves input order.
+//
+// The first call to return a non-nil error, or ctx being done, stops the
+// stage: in is no longer read, the context passed to the remaining calls is
+// canceled, and once they have returned the error is sent on the returned
+// error channel. Unless ctx is done, results of calls that succeed are still
+// delivered after a failure, so a best-effort consumer keeps every result
+// that was computed. Both channels are closed when the stage is finished,
+// after in is closed or the stage has stopped and every call has returned,
+// so receiving from the error channel after draining the value channel
+// yields nil on success. The value channel must be drained, or ctx canceled
+// to abandon the stage. As with WithContext, a limit less than or equal to
+// zero selects the default limit.
+func Transform[T, R any](ctx context.Context, limit int64, in <-chan T, fn func(context.Context, T) (R, error)) (<-chan R, <-chan error) {
+	return TransformCost(ctx, limit, in, nil, fn)
+}
+
+// TransformCost is like Transform, but each call occupies as many slots as
+// cost returns for its value, as with GoCost, so that limit caps the total
+// cost of the calls in flight, e.g. bytes rather than a number of values. A
+// nil cost counts every value as one.
+func TransformCost[T, R any](ctx context.Context, limit int64, in <-chan T, cost func(T) int64, fn func(context.Context, T) (R, error)) (<-chan R, <-chan error) {
+	out := make(chan R)
+	errc := make(chan error, 1)
+
+	parent := ctx
+	lg, ctx := WithContext(ctx, limit)
+	go func() {
+		defer close(errc)
+
+	recv:
+		for {
+			select {
+			case <-ctx.Done():
+				break recv
+			case v, ok := <-in:
+				if !ok {
+					break recv
+				}
+				var c int64
+				if cost != nil {
+					c = cost(v)
+				}
+				lg.GoCost(c, func() error {
+					r, err := fn(ctx, v)
+					if err != nil {
+						return err
+					}
+					select {
+					case out <- r:
+						return nil
+					case <-parent.Done():
+						return parent.Err()
+					}
+				})
+			}
+		}
+
+		err := lg.Wait()
+		if err == nil {
+			err = parent.Err()
+		}
+		close(out)
+		if err != nil {
+			errc <- err
+		}
+	}()
+
+	return out, errc
+}
@@ -0,0 +1,154 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// feed returns a channel on which the given values are sent, closed after
+// the last one.
+func feed[T any](values ...T) <-chan T {
+	in := make(chan T)
+	go func() {
+		defer close(in)
+		for _, v := range values {
+			in <- v
+		}
+	}()
+	return in
+}
+
+// peak tracks the highest of a running total.
+type peak struct {
+	cur, max int64
+}
+
+func (p *peak) add(n int64) {
+	cur := atomic.AddInt64(&p.cur, n)
+	for {
+		max := atomic.LoadInt64(&p.max)
+		if cur <= max || atomic.CompareAndSwapInt64(&p.max, max, cur) {
+			return
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	var p peak
+	out, errc := Transform(context.Background(), 3, feed(1, 2, 3, 4, 5, 6, 7, 8), func(_ context.Context, v int) (int, error) {
+		p.add(1)
+		defer p.add(-1)
+		time.Sleep(time.Millisecond)
+		return v * 10, nil
+	})
+
+	var got []int
+	for r := range out {
+		got = append(got, r)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("error channel yielded %v, want nil", err)
+	}
+	if _, ok := <-errc; ok {
+		t.Error("error channel not closed")
+	}
+
+	sort.Ints(got)
+	want := []int{10, 20, 30, 40, 50, 60, 70, 80}
+	if len(got) != len(want) {
+		t.Fatalf("results = %v, want %v in any order", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("results = %v, want %v in any order", got, want)
+		}
+	}
+	if p.max > 3 {
+		t.Errorf("%d calls in flight at once, want at most 3", p.max)
+	}
+}
+
+func TestTransformError(t *testing.T) {
+	errBad := errors.New("bad")
+	in, stop := make(chan int), make(chan struct{})
+	defer close(stop)
+	go func() {
+		// Never closed: the stage must stop reading on its own.
+		for i := 0; ; i++ {
+			select {
+			case in <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	out, errc := Transform(context.Background(), 2, in, func(ctx context.Context, v int) (int, error) {
+		if v == 3 {
+			return 0, errBad
+		}
+		if v > 3 {
+			// Only returns once the failure cancels the call.
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return v, nil
+	})
+
+	for range out {
+	}
+	if err := <-errc; !errors.Is(err, errBad) {
+		t.Errorf("error channel yielded %v, want %v", err, errBad)
+	}
+	if _, ok := <-errc; ok {
+		t.Error("error channel not closed")
+	}
+}
+
+func TestTransformCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	out, errc := Transform(ctx, 2, feed(1, 2, 3), func(_ context.Context, v int) (int, error) {
+		return v, nil
+	})
+
+	// Abandon the stage without draining it.
+	cancel()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error channel yielded %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stage did not stop once ctx was canceled")
+	}
+	for range out {
+	}
+}
+
+func TestTransformCost(t *testing.T) {
+	var p peak
+	out, errc := TransformCost(context.Background(), 4, feed[int64](1, 3, 2, 2, 4, 1), func(v int64) int64 { return v }, func(_ context.Context, v int64) (int64, error) {
+		p.add(v)
+		defer p.add(-v)
+		time.Sleep(time.Millisecond)
+		return v, nil
+	})
+
+	var sum int64
+	for r := range out {
+		sum += r
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("error channel yielded %v, want nil", err)
+	}
+	if sum != 13 {
+		t.Errorf("sum of results = %d, want 13", sum)
+	}
+	if p.max > 4 {
+		t.Errorf("cost %d in flight at once, want at most 4", p.max)
+	}
+}
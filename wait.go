@@ -0,0 +1,141 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxUnfinishedListed is the number of running subtasks named in the message
+// of an UnfinishedError.
+const maxUnfinishedListed = 10
+
+// An UnfinishedError is returned by WaitContext and WaitTimeout when they stop
+// waiting before every subtask has returned. It lists the subtasks that were
+// still running at the time, longest running first.
+type UnfinishedError struct {
+	// Err is the reason waiting stopped, e.g. context.DeadlineExceeded.
+	Err error
+	// Running describes the subtasks that were still running.
+	Running []TaskInfo
+	// At is when waiting stopped.
+	At time.Time
+}
+
+func (e *UnfinishedError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "limitgroup: %v with %d subtasks still running", e.Err, len(e.Running))
+	for i, ti := range e.Running {
+		if i == maxUnfinishedListed {
+			fmt.Fprintf(&b, ", and %d more", len(e.Running)-i)
+			break
+		}
+		sep := ", "
+		if i == 0 {
+			sep = ": "
+		}
+		fmt.Fprintf(&b, "%s%s (%v)", sep, ti.label(), e.At.Sub(ti.Start).Round(time.Millisecond))
+	}
+	return b.String()
+}
+
+func (e *UnfinishedError) Unwrap() error {
+	return e.Err
+}
+
+// WaitContext is like Wait, but stops waiting when ctx is done, returning an
+// *UnfinishedError that names the subtasks still running. The subtasks are
+// not canceled and carry on in the background, and the Group stays open:
+// it is only closed, as by Wait, by a call that returns its result.
+//
+// Functions passed directly to a wrapped errgroup.Group are not covered by
+// ctx; once the Group's own subtasks have returned, WaitContext waits for
+// them as Wait does.
+func (lg *Group) WaitContext(ctx context.Context) error {
+	for {
+		lg.idleMu.Lock()
+		active, idle := lg.active, lg.idle
+		lg.idleMu.Unlock()
+		if active == 0 {
+			return lg.Wait()
+		}
+
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return &UnfinishedError{Err: ctx.Err(), Running: lg.Running(), At: time.Now()}
+		}
+	}
+}
+
+// spawn calls f in a new goroutine of the Group's errgroup.Group, keeping
+// track of it so that WaitContext can tell when the Group is idle without
+// calling Wait.
+func (lg *Group) spawn(f func() error) {
+	lg.idleMu.Lock()
+	if lg.active == 0 {
+		lg.idle = make(chan struct{})
+	}
+	lg.active++
+	lg.idleMu.Unlock()
+
+	lg.eg.Go(func() error {
+		defer func() {
+			lg.idleMu.Lock()
+			if lg.active--; lg.active == 0 {
+				close(lg.idle)
+			}
+			lg.idleMu.Unlock()
+		}()
+		return f()
+	})
+}
+
+// WaitTimeout is like WaitContext, but stops waiting after d.
+func (lg *Group) WaitTimeout(d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return lg.WaitContext(ctx)
+}
+
+// WaitAll waits for every one of the given Groups concurrently, as if by
+// calling WaitContext on each, and returns the errors they returned joined
+// with errors.Join, in the order the Groups were given. If ctx is done first,
+// each Group still waiting contributes an *UnfinishedError to the result.
+func WaitAll(ctx context.Context, groups ...*Group) error {
+	errs := make([]error, len(groups))
+	done := make(chan struct{})
+	for i, lg := range groups {
+		i, lg := i, lg
+		go func() {
+			errs[i] = lg.WaitContext(ctx)
+			done <- struct{}{}
+		}()
+	}
+	for range groups {
+		<-done
+	}
+	return errors.Join(errs...)
+}
+
+// Running returns the subtasks that are currently running, longest running
+// first.
+func (lg *Group) Running() []TaskInfo {
+	lg.mu.Lock()
+	running := make([]TaskInfo, 0, len(lg.running))
+	for _, ti := range lg.running {
+		running = append(running, ti)
+	}
+	lg.mu.Unlock()
+
+	sort.Slice(running, func(i, j int) bool {
+		if !running[i].Start.Equal(running[j].Start) {
+			return running[i].Start.Before(running[j].Start)
+		}
+		return running[i].ID < running[j].ID
+	})
+	return running
+}
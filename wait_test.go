@@ -0,0 +1,83 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitTimeoutUnfinished(t *testing.T) {
+	lg, _ := WithContext(context.Background(), 2)
+	release := make(chan struct{})
+	lg.GoNamed("stuck", func() error {
+		<-release
+		return nil
+	})
+	lg.Go(func() error { return nil })
+
+	err := lg.WaitTimeout(10 * time.Millisecond)
+	var unfinished *UnfinishedError
+	if !errors.As(err, &unfinished) {
+		t.Fatalf("WaitTimeout() = %v, want an *UnfinishedError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitTimeout() = %v, want it to match context.DeadlineExceeded", err)
+	}
+	if len(unfinished.Running) != 1 || unfinished.Running[0].Name != "stuck" {
+		t.Errorf("Running = %v, want just the stuck subtask", unfinished.Running)
+	}
+	if !strings.Contains(err.Error(), "stuck (") {
+		t.Errorf("Error() = %q, want it to name the stuck subtask", err)
+	}
+
+	// The Group is still open after WaitTimeout gives up.
+	lg.Go(func() error { return nil })
+	close(release)
+	if err := lg.WaitContext(context.Background()); err != nil {
+		t.Errorf("WaitContext() = %v, want nil", err)
+	}
+}
+
+func TestWaitContextResult(t *testing.T) {
+	errBad := errors.New("bad")
+	lg, _ := WithContext(context.Background(), 1)
+	lg.Go(func() error { return errBad })
+
+	if err := lg.WaitContext(context.Background()); !errors.Is(err, errBad) {
+		t.Errorf("WaitContext() = %v, want %v", err, errBad)
+	}
+	if _, err := lg.Reserve(context.Background(), 1); !errors.Is(err, ErrGroupClosed) {
+		t.Errorf("Reserve() after WaitContext returned = %v, want ErrGroupClosed", err)
+	}
+}
+
+func TestUnfinishedErrorMessage(t *testing.T) {
+	at := time.Now()
+	running := make([]TaskInfo, maxUnfinishedListed+2)
+	for i := range running {
+		running[i] = TaskInfo{ID: i, Start: at.Add(-time.Second)}
+	}
+	err := &UnfinishedError{Err: context.Canceled, Running: running, At: at}
+
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "limitgroup: context canceled with 12 subtasks still running: #0 (1s)") {
+		t.Errorf("Error() = %q, want it to list the first subtask", msg)
+	}
+	if !strings.HasSuffix(msg, ", and 2 more") {
+		t.Errorf("Error() = %q, want it to elide all but %d subtasks", msg, maxUnfinishedListed)
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	errA := errors.New("a")
+	a, _ := WithContext(context.Background(), 1)
+	b, _ := WithContext(context.Background(), 1)
+	a.Go(func() error { return errA })
+	b.Go(func() error { return nil })
+
+	if err := WaitAll(context.Background(), a, b); !errors.Is(err, errA) {
+		t.Errorf("WaitAll() = %v, want %v", err, errA)
+	}
+}
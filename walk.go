@@ -0,0 +1,156 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory in the tree, including root, with the same arguments as
+// fs.WalkDir, and returns the same error.
+//
+// Directories are read concurrently, with at most limit in flight at once,
+// while the entries of each directory are passed to fn sequentially in
+// lexical order. fn must therefore be safe to call from multiple goroutines.
+// fs.SkipDir skips a directory, or the rest of a file's directory, exactly as
+// for fs.WalkDir. The walk is deterministic in its result: WalkDir returns
+// the error that fs.WalkDir would have returned, that is, the one reported
+// for the path that comes first in lexical walk order, or nil for
+// fs.SkipAll. Since later directories are walked alongside earlier ones,
+// though, fn may already have been called for paths after the one that
+// returned fs.SkipAll or an error, which fs.WalkDir would not have visited;
+// only once that return is recorded are the remaining paths skipped. As with
+// WithContext, a limit less than or equal to zero selects the default limit.
+func WalkDir(ctx context.Context, limit int64, fsys fs.FS, root string, fn fs.WalkDirFunc) error {
+	w := &walker{fsys: fsys, fn: fn}
+	w.lg, w.ctx = WithContext(ctx, limit)
+
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else if err = fn(root, fs.FileInfoToDirEntry(info), nil); err == nil && info.IsDir() {
+		w.dir(root, nil, fs.FileInfoToDirEntry(info))
+	}
+	if err != nil {
+		w.stop(nil, err)
+	}
+
+	w.lg.Wait()
+
+	switch {
+	case w.err == nil:
+		return ctx.Err()
+	case errors.Is(w.err, fs.SkipDir), errors.Is(w.err, fs.SkipAll):
+		return nil
+	default:
+		return w.err
+	}
+}
+
+// walker holds the state of a WalkDir call.
+type walker struct {
+	fsys fs.FS
+	fn   fs.WalkDirFunc
+	lg   *Group
+	ctx  context.Context
+
+	mu  sync.Mutex
+	key []string // position of err in walk order
+	err error
+}
+
+// dir schedules the directory at name, whose position in walk order is key,
+// to be read and walked. Like GoAfter, it never blocks, so tasks can schedule
+// their subdirectories without holding their slot waiting for another one.
+func (w *walker) dir(name string, key []string, d fs.DirEntry) {
+	t := w.lg.newTask("")
+	w.lg.spawn(func() error {
+		if err := w.lg.acquire(w.ctx, &t); err != nil {
+			// Only possible once ctx is done, which WalkDir reports.
+			w.lg.shedTask(t, err)
+			return nil
+		}
+		return w.lg.call(t, func() error {
+			w.walk(name, key, d)
+			return nil
+		})
+	})
+}
+
+// walk reads the directory at name and passes its entries to fn.
+func (w *walker) walk(name string, key []string, d fs.DirEntry) {
+	if w.skip(key) {
+		return
+	}
+
+	entries, err := fs.ReadDir(w.fsys, name)
+	if err != nil {
+		// Report the error with a second call for the directory, as
+		// fs.WalkDir does.
+		if err = w.fn(name, d, err); err != nil && !errors.Is(err, fs.SkipDir) {
+			w.stop(key, err)
+			return
+		}
+	}
+
+	for _, e := range entries {
+		child := append(key[:len(key):len(key)], e.Name())
+		if w.skip(child) {
+			return
+		}
+
+		p := path.Join(name, e.Name())
+		if err := w.fn(p, e, nil); err != nil {
+			if errors.Is(err, fs.SkipDir) {
+				if e.IsDir() {
+					continue
+				}
+				// SkipDir for a file skips the rest of its directory.
+				return
+			}
+			w.stop(child, err)
+			return
+		}
+		if e.IsDir() {
+			w.dir(p, child, e)
+		}
+	}
+}
+
+// stop records err as having been reported for the path at key, unless an
+// error has already been reported for an earlier path.
+func (w *walker) stop(key []string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err == nil || walkBefore(key, w.key) {
+		w.key, w.err = key, err
+	}
+}
+
+// skip reports whether the path at key need not be visited, either because
+// ctx is done or because an error was reported for an earlier path.
+func (w *walker) skip(key []string) bool {
+	if w.ctx.Err() != nil {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err != nil && !walkBefore(key, w.key)
+}
+
+// walkBefore reports whether the path at a comes before the one at b in
+// lexical walk order, where a directory comes before its contents.
+func walkBefore(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,94 @@
+package limitgroup
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalkDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/sub/f": {},
+		"a/x":     {},
+		"a/y":     {},
+		"b/c/f":   {},
+		"b/x":     {},
+		"c":       {},
+		"d/e":     {},
+	}
+	errA := errors.New("a")
+	errB := errors.New("b")
+
+	tests := []struct {
+		name string
+		root string
+		// returns maps paths to what fn returns for them.
+		returns map[string]error
+		// stops is whether the walk ends early, in which case WalkDir may
+		// visit more paths than fs.WalkDir.
+		stops bool
+	}{
+		{name: "full walk", root: "."},
+		{name: "subtree", root: "b"},
+		{name: "file root", root: "c"},
+		{name: "missing root", root: "z", returns: map[string]error{"z": errA}, stops: true},
+		{name: "SkipDir for a directory", root: ".", returns: map[string]error{"a": fs.SkipDir, "b/c": fs.SkipDir}},
+		{name: "SkipDir for a file", root: ".", returns: map[string]error{"a/x": fs.SkipDir}},
+		{name: "SkipDir for root", root: ".", returns: map[string]error{".": fs.SkipDir}},
+		{name: "SkipAll", root: ".", returns: map[string]error{"a/y": fs.SkipAll}, stops: true},
+		{name: "error", root: ".", returns: map[string]error{"a/sub": errA}, stops: true},
+		{name: "earliest error wins", root: ".", returns: map[string]error{"d/e": errB, "b/x": errA}, stops: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu  sync.Mutex
+				got = make(map[string]bool)
+			)
+			gotErr := WalkDir(context.Background(), 4, fsys, tt.root, func(path string, d fs.DirEntry, err error) error {
+				mu.Lock()
+				got[path] = true
+				mu.Unlock()
+				return tt.returns[path]
+			})
+
+			var want []string
+			wantErr := fs.WalkDir(fsys, tt.root, func(path string, d fs.DirEntry, err error) error {
+				want = append(want, path)
+				return tt.returns[path]
+			})
+
+			if gotErr != wantErr {
+				t.Errorf("WalkDir() = %v, want %v", gotErr, wantErr)
+			}
+			for _, p := range want {
+				if !got[p] {
+					t.Errorf("fn not called for %q", p)
+				}
+			}
+			if !tt.stops && len(got) != len(want) {
+				paths := make([]string, 0, len(got))
+				for p := range got {
+					paths = append(paths, p)
+				}
+				sort.Strings(paths)
+				t.Errorf("fn called for %q, want %q", paths, want)
+			}
+		})
+	}
+}
+
+func TestWalkDirCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fsys := fstest.MapFS{"a/b": {}}
+	err := WalkDir(ctx, 1, fsys, ".", func(string, fs.DirEntry, error) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WalkDir() = %v, want context.Canceled", err)
+	}
+}